@@ -8,7 +8,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/x2ox/memo/db"
 	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/telegram"
 	"github.com/x2ox/memo/tpl"
+	"go.uber.org/zap"
 )
 
 func previewAction(c *gin.Context) {
@@ -16,12 +18,12 @@ func previewAction(c *gin.Context) {
 	fmt.Println("key:", hex.EncodeToString(ar[:]))
 	tokenStr := c.Param("token")
 	tk := model.ParseToken(tokenStr)
-	if tk == nil || !tk.Valid() {
+	if tk == nil || tk.Type == model.Graph || !tk.Valid() {
 		c.AbortWithStatus(http.StatusNotFound)
 		return
 	}
 	c.SetSameSite(http.SameSiteStrictMode)
-	c.SetCookie("Token", tokenStr, 0, "", "", true, true)
+	c.SetCookie("Token", tokenStr, 0, "/file", "", true, true)
 
 	if tk.NoteID == 0 {
 		c.HTML(http.StatusOK, "tpl.html", tpl.ToHTML(db.Input.String()))
@@ -33,5 +35,40 @@ func previewAction(c *gin.Context) {
 		c.Status(http.StatusNotFound)
 		return
 	}
+	db.Note.View(note.ID)
+	if tk.Type != model.Share { // 分享的页面不显示其他笔记的链接，也不能勾选任务
+		db.Note.Resolve(note)
+		note.Editable = true
+	}
 	c.HTML(http.StatusOK, "tpl.html", note.HTML())
 }
+
+type taskForm struct {
+	Offset *int `json:"offset" binding:"required,min=0"`
+	Done   bool `json:"done"`
+}
+
+// taskAction 预览页面中勾选任务，只接受阅读及预览笔记的 Token
+func taskAction(c *gin.Context) {
+	tk := model.ParseToken(c.Param("token"))
+	if tk == nil || tk.NoteID == 0 || (tk.Type != model.View && tk.Type != model.Preview) || !tk.Valid() {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	var form taskForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	switch note, err := db.Note.SetTask(tk.NoteID, *form.Offset, form.Done); err {
+	case nil:
+		telegram.EmbedLater(note)
+		c.Status(http.StatusNoContent)
+	case db.ErrNoTask:
+		c.AbortWithStatus(http.StatusNotFound)
+	default:
+		log.Error("set task error", zap.Uint64("id", tk.NoteID), zap.Error(err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+)
+
+// fileAction 附件下载，支持 Range、ETag 与原始文件名
+func fileAction(c *gin.Context) {
+	name := filepath.Base(c.Param("name"))
+	if tk, ok := c.MustGet(tokenKey).(*model.Token); !ok || !fileAllowed(tk, name) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	file := db.File.GetWithName(name)
+	if file == nil {
+		file = &model.File{Name: name}
+	}
+
+	f, err := os.Open(file.Path())
+	if err != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if file.MimeType != "" {
+		c.Header("Content-Type", file.MimeType)
+	}
+	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()))
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("Content-Disposition", contentDisposition(file.DisplayName()))
+
+	http.ServeContent(c.Writer, c.Request, file.Name, fi.ModTime(), f)
+}
+
+// fileAllowed 只允许访问 Token 对应笔记中引用的附件，草稿箱的附件仅限预览 Token
+func fileAllowed(tk *model.Token, name string) bool {
+	if tk.NoteID == 0 {
+		return tk.Type == model.Preview && model.HasAttachment(db.Input.String(), name)
+	}
+
+	note := db.Note.GetWithID(tk.NoteID)
+	return note != nil && model.HasAttachment(note.Content, name)
+}
+
+// contentDisposition RFC 6266，filename 作为 ASCII 回退，filename* 保留原始文件名
+func contentDisposition(name string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+	encoded := strings.ReplaceAll(url.QueryEscape(name), "+", "%20")
+	return fmt.Sprintf(`inline; filename="%s"; filename*=UTF-8''%s`, fallback, encoded)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+)
+
+// graphAuth 只接受关系图的 Token
+func graphAuth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		tk := model.ParseToken(c.Param("token"))
+		if tk == nil || tk.Type != model.Graph || !tk.Valid() {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Next()
+	}
+}
+
+func graphAction(c *gin.Context) {
+	c.HTML(http.StatusOK, "graph.html", "/graph/"+c.Param("token")+"/data")
+}
+
+func graphDataAction(c *gin.Context) {
+	g := db.Note.Graph()
+	for i := range g.Nodes {
+		g.Nodes[i].URL = model.GraphNoteLink(c.Param("token"), g.Nodes[i].ID)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, g)
+}
+
+// graphNoteAction 跳转到笔记的阅读链接
+func graphNoteAction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	note := db.Note.GetWithID(id)
+	if note == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	c.Redirect(http.StatusFound, note.ViewLink())
+}
@@ -13,6 +13,8 @@ import (
 
 var log *zap.Logger
 
+const tokenKey = "token"
+
 func Router() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
@@ -35,11 +37,21 @@ func Router() *gin.Engine {
 	}
 
 	engine.GET("/preview/:token", previewAction)
+	engine.POST("/preview/:token/task", taskAction)
+
+	{
+		graph := engine.Group("/graph/:token")
+		graph.Use(graphAuth())
+		graph.GET("", graphAction)
+		graph.GET("/data", graphDataAction)
+		graph.GET("/note/:id", graphNoteAction)
+	}
 
 	{
-		static := engine.Group("/file")
-		static.Use(authAction())
-		static.Static("/", model.Conf.StaticFolder())
+		file := engine.Group("/file")
+		file.Use(authAction())
+		file.GET("/:name", fileAction)
+		file.HEAD("/:name", fileAction)
 	}
 
 	return engine
@@ -52,10 +64,12 @@ func authAction() func(c *gin.Context) {
 			c.AbortWithStatus(http.StatusNotFound)
 			return
 		}
-		if tk := model.ParseToken(ts); tk == nil || !tk.Valid() {
+		tk := model.ParseToken(ts)
+		if tk == nil || !tk.Valid() {
 			c.AbortWithStatus(http.StatusNotFound)
 			return
 		}
+		c.Set(tokenKey, tk)
 		c.Next()
 	}
 }
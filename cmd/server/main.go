@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,10 +17,14 @@ import (
 	"go.x2ox.com/blackdatura"
 )
 
-var log *zap.Logger
+var (
+	log     *zap.Logger
+	reindex = flag.Bool("reindex", false, "rebuild the full-text search index and exit")
+)
 
 func init() {
-	model.LoadConfig()
+	flag.Parse()
+	model.LoadConfig(flag.Arg(0))
 	blackdatura.Init(model.Conf.LogLevel, true,
 		blackdatura.Lumberjack(model.Conf.LogFolder(), 1024, 30, 90, true))
 	log = blackdatura.New()
@@ -31,6 +36,15 @@ func init() {
 func main() {
 	participle.Init(model.Conf.DataFolder)
 	db.Init()
+	if *reindex {
+		if err := db.ReIndex(func(done, total int64) {
+			log.Info("[Memo] reindex", zap.Int64("done", done), zap.Int64("total", total))
+		}); err != nil {
+			log.Fatal("[Memo] reindex error", zap.Error(err))
+		}
+		log.Info("[Memo] reindex finished")
+		return
+	}
 	telegram.Init()
 
 	router := api.Router()
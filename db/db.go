@@ -2,8 +2,10 @@ package db
 
 import (
 	"bytes"
+	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -35,10 +37,26 @@ func Init() {
 		log.Fatal("gorm client db fail", zap.Error(err))
 	}
 
-	if err = db.AutoMigrate(&model.Note{}, &model.Input{}); err != nil {
+	hasTodo := db.Migrator().HasColumn(&model.Note{}, "todo")
+	if err = db.AutoMigrate(&model.Note{}, &model.Input{}, &model.File{}, &model.History{}, &model.NoteLink{},
+		&model.NoteTemplate{}, &model.Reminder{}, &model.Review{}, &model.ScheduleRun{}); err != nil {
 		log.Fatal("gorm auto migrate fail", zap.Error(err))
 	}
-	Search = Search.New(db)
+	if !hasTodo {
+		if err = initTodo(); err != nil {
+			log.Fatal("todo init err", zap.Error(err))
+		}
+	}
+	if err = initVector(); err != nil {
+		log.Fatal("vector init err", zap.Error(err))
+	}
+	if err = initWords(); err != nil {
+		log.Fatal("word init err", zap.Error(err))
+	}
+	if err = initTags(); err != nil {
+		log.Fatal("tag init err", zap.Error(err))
+	}
+	Search = newSearch().New(db)
 	if err = Search.Init(); err != nil {
 		log.Fatal("full text search init err", zap.Error(err))
 	}
@@ -47,9 +65,77 @@ func Init() {
 	}
 }
 
+func newSearch() FullTextSearch {
+	switch {
+	case model.Conf.IsInverted():
+		return &Inverted{}
+	case model.Conf.IsIndex():
+		return &FileIndex{}
+	case model.Conf.IsPostgreSQL():
+		return &PostgreSQL{}
+	}
+	return &SQLite{}
+}
+
+// ErrReIndexing 已经有重建索引的任务在执行
+var ErrReIndexing = errors.New("reindex is running")
+
+var reindexing int32
+
+// ReIndex 重建全文索引，期间提交草稿需要等待
+func ReIndex(progress Progress) error {
+	if !atomic.CompareAndSwapInt32(&reindexing, 0, 1) {
+		return ErrReIndexing
+	}
+	defer atomic.StoreInt32(&reindexing, 0)
+
+	Input.mux.Lock()
+	defer Input.mux.Unlock()
+	return Search.ReIndex(progress)
+}
+
+// ReIndexNotes 只重建部分笔记的索引，期间提交草稿需要等待。在事务中重新读取笔记，避免使用过期的内容
+func ReIndexNotes(arr []*model.Note) error {
+	if !atomic.CompareAndSwapInt32(&reindexing, 0, 1) {
+		return ErrReIndexing
+	}
+	defer atomic.StoreInt32(&reindexing, 0)
+
+	Input.mux.Lock()
+	defer Input.mux.Unlock()
+
+	ids := make([]uint64, 0, len(arr))
+	for _, v := range arr {
+		ids = append(ids, v.ID)
+	}
+	return transaction(func(tx *gorm.DB) error {
+		var notes []*model.Note
+		if err := tx.Model(&model.Note{}).Where("id IN ?", ids).Find(&notes).Error; err != nil {
+			return err
+		}
+		for _, v := range notes {
+			if err := Search.New(tx).Delete(v.ID); err != nil {
+				return err
+			}
+			if err := createIndex(tx, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 var (
-	Note  = &noteSrv{}
-	Input = &inputSrv{mux: &sync.RWMutex{}}
+	Note     = &noteSrv{}
+	Input    = &inputSrv{mux: &sync.RWMutex{}}
+	File     = &fileSrv{}
+	History  = &historySrv{}
+	Vector   = &vectorSrv{}
+	Template = &templateSrv{}
+	Journal  = &journalSrv{}
+	Reminder = &reminderSrv{}
+	Review   = &reviewSrv{}
+	Schedule = &scheduleSrv{}
 )
 
 type (
@@ -88,14 +174,74 @@ func (srv *noteSrv) GetWithID(id uint64) *model.Note {
 }
 
 func (srv *noteSrv) Delete(id uint64) error {
-	return db.Transaction(func(tx *gorm.DB) error {
+	return transaction(func(tx *gorm.DB) error {
 		if err := Search.New(tx).Delete(id); err != nil {
 			return err
 		}
+		if err := tx.Where("note_id = ?", id).Delete(&noteWord{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", id).Delete(&noteTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", id).Delete(&noteVector{}).Error; err != nil {
+			return err
+		}
+		if err := unlinkNote(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", id).Delete(&model.Reminder{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ?", id).Delete(&model.Review{}).Error; err != nil {
+			return err
+		}
 		return tx.Delete(&model.Note{ID: id}).Error
 	})
 }
 
+// Contains 标题或正文中包含 s 的笔记
+func (srv *noteSrv) Contains(s string) []*model.Note {
+	var arr []*model.Note
+	like := "%" + escapeLike(s) + "%"
+	if err := db.Model(&model.Note{}).
+		Where(`title LIKE ? ESCAPE '\' OR content LIKE ? ESCAPE '\'`, like, like).
+		Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+func (srv *noteSrv) Pin(id uint64, pinned bool) error {
+	tx := db.Model(&model.Note{}).Where("id = ?", id).UpdateColumn("pinned", pinned)
+	if tx.Error == nil && tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return tx.Error
+}
+
+// View 记录查看的时间，不修改 updated_at
+func (srv *noteSrv) View(id uint64) {
+	db.Model(&model.Note{}).Where("id = ?", id).UpdateColumn("viewed_at", time.Now())
+}
+
+// Recent 置顶的笔记，之后为最近查看过的笔记
+func (srv *noteSrv) Recent(limit int) []*model.Note {
+	var pinned, viewed []*model.Note
+	if err := db.Model(&model.Note{}).Where("pinned = ?", true).
+		Order("updated_at DESC").Limit(limit).Find(&pinned).Error; err != nil {
+		return nil
+	}
+	if len(pinned) >= limit {
+		return pinned
+	}
+	if err := db.Model(&model.Note{}).Where("pinned = ? AND viewed_at IS NOT NULL", false).
+		Order("viewed_at DESC").Limit(limit - len(pinned)).Find(&viewed).Error; err != nil {
+		return pinned
+	}
+	return append(pinned, viewed...)
+}
+
 func (srv *noteSrv) Count() (i int64) {
 	db.Model(&model.Note{}).Count(&i)
 	return i
@@ -109,17 +255,20 @@ func (srv *inputSrv) String() string {
 	srv.mux.RLock()
 	defer srv.mux.RUnlock()
 	var i []string
-	if err := db.Model(&model.Input{}).Select("content").Find(&i).Error; err != nil {
+	if err := db.Model(&model.Input{}).Select("content").Where("title = ?", false).Find(&i).Error; err != nil {
 		return ""
 	}
 	return strings.Join(i, "\n")
 }
 
+// inputOrder 草稿按消息的顺序排列，模板展开的内容使用负的消息 ID
+const inputOrder = "ABS(message_id), id"
+
 func (srv *inputSrv) FindAll() []*model.Input {
 	srv.mux.RLock()
 	defer srv.mux.RUnlock()
 	var arr []*model.Input
-	if err := db.Model(&model.Input{}).Order("message_id").Find(&arr).Error; err != nil {
+	if err := db.Model(&model.Input{}).Where("title = ?", false).Order(inputOrder).Find(&arr).Error; err != nil {
 		return nil
 	}
 	return arr
@@ -131,7 +280,7 @@ func (srv *inputSrv) Check() bool {
 func (srv *inputSrv) Count() (i int64) {
 	srv.mux.RLock()
 	defer srv.mux.RUnlock()
-	if err := db.Model(&model.Input{}).Count(&i).Error; err != nil {
+	if err := db.Model(&model.Input{}).Where("title = ?", false).Count(&i).Error; err != nil {
 	}
 	return i
 }
@@ -141,22 +290,32 @@ func (srv *inputSrv) Submit() *model.Note {
 	defer srv.mux.Unlock()
 
 	var note *model.Note
-	if err := db.Transaction(func(tx *gorm.DB) error {
+	if err := transaction(func(tx *gorm.DB) error {
 		var arr []*model.Input
-		if err := tx.Model(&model.Input{}).Order("message_id").Find(&arr).Error; err != nil {
+		if err := tx.Model(&model.Input{}).Order(inputOrder).Find(&arr).Error; err != nil {
 			return err
 		}
 
-		var buf bytes.Buffer
+		var (
+			buf   bytes.Buffer
+			title string
+		)
 		for _, v := range arr {
+			if v.Title {
+				title = v.Content
+				continue
+			}
 			buf.WriteString(v.Content)
 		}
 
-		note = model.NewNote(buf.String())
+		note = model.NewNote(title, buf.String())
 		if err := tx.Create(note).Error; err != nil {
 			return err
 		}
-		if err := Search.New(tx).Create(note.ParticipleTitle(), note.ParticipleContent(), note.ID); err != nil {
+		if err := createIndex(tx, note); err != nil {
+			return err
+		}
+		if err := linkNote(tx, note); err != nil {
 			return err
 		}
 
@@ -171,7 +330,7 @@ func (srv *inputSrv) Submit() *model.Note {
 func (srv *inputSrv) UpdateContent(messageID int, content string) error {
 	srv.mux.Lock()
 	defer srv.mux.Unlock()
-	return db.Model(&model.Input{}).Where("message_id", messageID).
+	return db.Model(&model.Input{}).Where("message_id = ? AND title = ?", messageID, false).
 		Update("content", content).Error
 }
 
@@ -186,3 +345,38 @@ func (srv *inputSrv) Clear() error {
 	defer srv.mux.Unlock()
 	return db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&model.Input{}).Error
 }
+
+// Title /title 设置的标题
+func (srv *inputSrv) Title() string {
+	srv.mux.RLock()
+	defer srv.mux.RUnlock()
+	var i model.Input
+	db.Model(&model.Input{}).Where("title = ?", true).Limit(1).Find(&i)
+	return i.Content
+}
+
+// UpdateTitle 编辑了设置标题的 /title 消息，该标题已被之后的 /title 替换时不处理
+func (srv *inputSrv) UpdateTitle(messageID int, title string) error {
+	srv.mux.Lock()
+	defer srv.mux.Unlock()
+	tx := db.Where("message_id = ? AND title = ?", messageID, true)
+	if title == "" {
+		return tx.Delete(&model.Input{}).Error
+	}
+	return tx.Model(&model.Input{}).Update("content", title).Error
+}
+
+// SetTitle 设置草稿箱的标题，为空时清除
+func (srv *inputSrv) SetTitle(messageID int, title string) error {
+	srv.mux.Lock()
+	defer srv.mux.Unlock()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("title = ?", true).Delete(&model.Input{}).Error; err != nil {
+			return err
+		}
+		if title == "" {
+			return nil
+		}
+		return tx.Create(&model.Input{MessageID: messageID, Content: title, Title: true}).Error
+	})
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"time"
+
+	"github.com/x2ox/memo/model"
+)
+
+// CreatedBetween [from, to) 之间新增的笔记，按时间先后排列
+func (srv *noteSrv) CreatedBetween(from, to time.Time) []*model.Note {
+	var arr []*model.Note
+	if err := db.Model(&model.Note{}).Where("created_at >= ? AND created_at < ?", from, to).
+		Order("created_at").Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+// EditedBetween [from, to) 之间编辑过的笔记，不包括期间新增的。只记录了最后一次编辑的时间，之后又编辑过的不在其中
+func (srv *noteSrv) EditedBetween(from, to time.Time) []*model.Note {
+	var arr []*model.Note
+	if err := db.Model(&model.Note{}).Where("updated_at >= ? AND updated_at < ? AND created_at < ?", from, to, from).
+		Order("updated_at").Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+// OnThisDay 往年同一天新增的笔记，day 为当天零点
+func (srv *noteSrv) OnThisDay(day time.Time) []*model.Note {
+	var first model.Note
+	if db.Model(&model.Note{}).Order("created_at").Limit(1).Find(&first).RowsAffected == 0 {
+		return nil
+	}
+
+	var arr []*model.Note
+	for i := 1; ; i++ {
+		d := day.AddDate(-i, 0, 0)
+		if d.AddDate(0, 0, 1).Before(first.CreatedAt) {
+			break
+		}
+		if d.Day() != day.Day() { // 闰年的 2 月 29 日
+			continue
+		}
+		var notes []*model.Note
+		if err := db.Model(&model.Note{}).Where("created_at >= ? AND created_at < ?", d, d.AddDate(0, 0, 1)).
+			Find(&notes).Error; err != nil {
+			return nil
+		}
+		arr = append(arr, notes...)
+	}
+	return arr
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/x2ox/memo/model"
+)
+
+type fileSrv struct{}
+
+func (srv *fileSrv) Add(f *model.File) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"original", "mime_type", "text", "updated_at"}),
+	}).Create(f).Error
+}
+
+func (srv *fileSrv) GetWithName(name string) *model.File {
+	var f model.File
+	if err := db.Model(&model.File{}).Where("name = ?", name).
+		First(&f).Error; err != nil {
+		return nil
+	}
+	return &f
+}
+
+// attachmentText 内容中引用的附件提取出的文字
+func attachmentText(tx *gorm.DB, content string) string {
+	names := model.Attachments(content)
+	if len(names) == 0 {
+		return ""
+	}
+	var arr []string
+	if err := tx.Model(&model.File{}).Where("name IN ?", names).
+		Where("text <> ''").Pluck("text", &arr).Error; err != nil {
+		return ""
+	}
+	return strings.Join(arr, "\n")
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/index"
+	"github.com/x2ox/memo/pkg/participle"
+	"github.com/x2ox/memo/pkg/query"
+	"go.uber.org/zap"
+	"go.x2ox.com/blackdatura"
+	"gorm.io/gorm"
+)
+
+// FileIndex 保存在数据目录中的索引，BM25 排序，支持前缀及模糊匹配，按标签及月份统计。
+// 索引全部加载到内存中，保存时整个文件重新写入
+type FileIndex struct {
+	db  *gorm.DB
+	idx *index.Index
+}
+
+func (f *FileIndex) New(db *gorm.DB) FullTextSearch { return &FileIndex{db: db, idx: f.idx} }
+
+func (f *FileIndex) Init() (err error) {
+	f.idx, err = index.Open(model.Conf.IndexFile())
+	return
+}
+
+// Index 索引文件丢失，或者笔记的 id 及修改时间与 note 表不一致时重建
+func (f *FileIndex) Index() error {
+	var arr []struct {
+		ID        uint64
+		UpdatedAt time.Time
+	}
+	if err := f.db.Model(&model.Note{}).Select("id, updated_at").Find(&arr).Error; err != nil {
+		return err
+	}
+	updated := make(map[uint64]time.Time, len(arr))
+	for _, v := range arr {
+		updated[v.ID] = v.UpdatedAt
+	}
+	if f.idx.Matches(updated) {
+		return nil
+	}
+	return f.ReIndex(nil)
+}
+
+// ReIndex 清空后分批写入，全部完成后才保存到文件
+func (f *FileIndex) ReIndex(progress Progress) error {
+	var total int64
+	if err := f.db.Model(&model.Note{}).Count(&total).Error; err != nil {
+		return err
+	}
+
+	f.idx.Reset()
+	var (
+		done int64
+		arr  []*model.Note
+	)
+	if err := f.db.Model(&model.Note{}).FindInBatches(&arr, rebuildBatch, func(*gorm.DB, int) error {
+		for _, v := range arr {
+			attachment := attachmentText(f.db, v.Content)
+			f.put(v, v.ParticipleTitle(), v.ParticipleContent(), participle.Parse(attachment))
+			if err := saveTags(f.db, v); err != nil {
+				return err
+			}
+			if err := saveWords(f.db, v, attachment); err != nil {
+				return err
+			}
+		}
+		done += int64(len(arr))
+		if progress != nil {
+			progress(done, total)
+		}
+		return nil
+	}).Error; err != nil {
+		return err
+	}
+	return f.idx.Save()
+}
+
+func (f *FileIndex) Clean() error {
+	f.idx.Reset()
+	if err := os.Remove(model.Conf.IndexFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Create 在事务中调用时，提交后才写入内存中的索引，回滚时索引不变
+func (f *FileIndex) Create(titleKeywords, contentKeywords, attachmentKeywords string, id uint64) error {
+	note := &model.Note{ID: id}
+	if err := f.db.Model(&model.Note{}).Where("id = ?", id).First(note).Error; err != nil {
+		return err
+	}
+	afterCommit(f.db, func() {
+		f.put(note, titleKeywords, contentKeywords, attachmentKeywords)
+		f.saveLater()
+	})
+	return nil
+}
+
+func (f *FileIndex) put(note *model.Note, titleKeywords, contentKeywords, attachmentKeywords string) {
+	f.idx.Put(note.ID, index.Doc{Tags: note.Tags(), Created: note.CreatedAt, Updated: note.UpdatedAt},
+		[index.FieldCount][]string{
+			strings.Fields(titleKeywords), strings.Fields(contentKeywords), strings.Fields(attachmentKeywords),
+		})
+}
+
+func (f *FileIndex) Delete(id uint64) error {
+	afterCommit(f.db, func() {
+		f.idx.Remove(id)
+		f.saveLater()
+	})
+	return nil
+}
+
+// fileIndexSaveDelay 修改后延迟保存，期间的修改合并为一次写入。
+// 进程在此之前退出时，启动时的 Index 会发现不一致并重建
+const fileIndexSaveDelay = 2 * time.Second
+
+var fileIndexSave struct {
+	sync.Mutex
+	timer *time.Timer
+}
+
+func (f *FileIndex) saveLater() {
+	fileIndexSave.Lock()
+	defer fileIndexSave.Unlock()
+	if fileIndexSave.timer != nil {
+		return
+	}
+	fileIndexSave.timer = time.AfterFunc(fileIndexSaveDelay, func() {
+		fileIndexSave.Lock()
+		fileIndexSave.timer = nil
+		fileIndexSave.Unlock()
+		if err := f.idx.Save(); err != nil {
+			blackdatura.With("index").Error("save index file error", zap.Error(err))
+		}
+	})
+}
+
+func (f *FileIndex) Search(q *query.Query, offset, limit int) ([]*model.Note, int64) {
+	arr, count, _ := f.SearchFacets(q, offset, limit)
+	return arr, count
+}
+
+// SearchFacets 有短语时取出全部结果验证后再分页，否则只取出当前页的笔记。统计与结果来自同一次搜索
+func (f *FileIndex) SearchFacets(q *query.Query, offset, limit int) (arr []*model.Note, count int64, facets index.Facets) {
+	if !q.HasText() {
+		arr, count = filterSearch(f.db, q, offset, limit)
+		return
+	}
+
+	result, facets := f.idx.Search(q)
+	hits := make([]hit, 0, len(result))
+	for _, v := range result {
+		hits = append(hits, hit{ID: v.ID, Attachment: v.Attachment})
+	}
+
+	if hasPhrase(q) {
+		for _, v := range hitNotes(f.db, hits) {
+			if phraseMatches(q, v) {
+				arr = append(arr, v)
+			}
+		}
+		count = int64(len(arr))
+		arr = pageNotes(arr, offset, limit)
+	} else {
+		count = int64(len(hits))
+		arr = hitNotes(f.db, pageHits(hits, offset, limit))
+	}
+
+	setSnippets(f.db, arr, q)
+	return
+}
+
+func hasPhrase(q *query.Query) bool {
+	for _, group := range q.Must {
+		for _, t := range group {
+			if t.Phrase {
+				return true
+			}
+		}
+	}
+	for _, t := range q.Not {
+		if t.Phrase {
+			return true
+		}
+	}
+	return false
+}
+
+// phraseMatches 全部为短语的组中至少有一个短语出现，排除的短语都不出现
+func phraseMatches(q *query.Query, n *model.Note) bool {
+	for _, group := range q.Must {
+		ok := false
+		for _, t := range group {
+			if !t.Phrase || contains(t, n) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, t := range q.Not {
+		if t.Phrase && contains(t, n) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,12 @@
 package db
 
 import (
+	"strings"
+
 	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/index"
+	"github.com/x2ox/memo/pkg/participle"
+	"github.com/x2ox/memo/pkg/query"
 	"gorm.io/gorm"
 )
 
@@ -10,12 +15,185 @@ type FullTextSearch interface {
 	New(db *gorm.DB) FullTextSearch
 	Init() error
 	Index() error
-	ReIndex() error
+	ReIndex(progress Progress) error
 	Clean() error
 
-	Search(keywords string, offset, limit int) ([]*model.Note, int64)
-	Create(titleKeywords, contentKeywords string, id uint64) error
+	// Search 结果中的 Note.Attachment 及 Note.Snippet 由各实现填充
+	Search(q *query.Query, offset, limit int) ([]*model.Note, int64)
+	Create(titleKeywords, contentKeywords, attachmentKeywords string, id uint64) error
 	Delete(id uint64) error
 }
 
 var Search FullTextSearch = &SQLite{}
+
+// Progress 重建索引的进度，每完成一批调用一次
+type Progress func(done, total int64)
+
+// Faceter 搜索的同时可以按标签及月份统计全部结果的实现
+type Faceter interface {
+	SearchFacets(q *query.Query, offset, limit int) ([]*model.Note, int64, index.Facets)
+}
+
+// createIndex 为笔记的标题、正文及附件中的文字建立索引
+func createIndex(tx *gorm.DB, note *model.Note) error { return indexNote(Search.New(tx), tx, note) }
+
+// indexNote 分词后写入 s，同时更新补全使用的词表及过滤使用的标签
+func indexNote(s FullTextSearch, tx *gorm.DB, note *model.Note) error {
+	attachment := attachmentText(tx, note.Content)
+	if err := s.Create(note.ParticipleTitle(), note.ParticipleContent(), participle.Parse(attachment), note.ID); err != nil {
+		return err
+	}
+	if err := saveTags(tx, note); err != nil {
+		return err
+	}
+	return saveWords(tx, note, attachment)
+}
+
+// rebuildBatch 重建索引时每批读取的笔记数量
+const rebuildBatch = 200
+
+// rebuild 从 note 表分批读取笔记，使用当前的分词重建全文索引，s 需要绑定在 tx 上
+func rebuild(s FullTextSearch, tx *gorm.DB, progress Progress) error {
+	var total int64
+	if err := tx.Model(&model.Note{}).Count(&total).Error; err != nil {
+		return err
+	}
+
+	var (
+		done int64
+		arr  []*model.Note
+	)
+	return tx.Model(&model.Note{}).FindInBatches(&arr, rebuildBatch, func(*gorm.DB, int) error {
+		for _, v := range arr {
+			if err := indexNote(s, tx, v); err != nil {
+				return err
+			}
+		}
+		done += int64(len(arr))
+		if progress != nil {
+			progress(done, total)
+		}
+		return nil
+	}).Error
+}
+
+// hit 全文搜索命中的结果
+type hit struct {
+	ID         uint64
+	Attachment bool   // 附件中的文字有匹配
+	Snippet    string // 匹配词附近的片段
+}
+
+// hitNotes 按命中的顺序取出笔记
+func hitNotes(tx *gorm.DB, hits []hit) []*model.Note {
+	if len(hits) == 0 {
+		return nil
+	}
+	ids := make([]uint64, 0, len(hits))
+	for _, v := range hits {
+		ids = append(ids, v.ID)
+	}
+
+	var arr []*model.Note
+	if err := tx.Model(&model.Note{}).Where("id IN ?", ids).Find(&arr).Error; err != nil {
+		return nil
+	}
+	m := make(map[uint64]*model.Note, len(arr))
+	for _, v := range arr {
+		m[v.ID] = v
+	}
+
+	notes := make([]*model.Note, 0, len(arr))
+	for _, v := range hits {
+		if n, ok := m[v.ID]; ok {
+			n.Attachment, n.Snippet = v.Attachment, v.Snippet
+			notes = append(notes, n)
+		}
+	}
+	return notes
+}
+
+// snippetWords 片段中标出的词。分词后的词可能是词干，如 happy 为 happi，同时使用搜索词的原文
+func snippetWords(q *query.Query) []string {
+	arr := lowerTokens(q.Tokens())
+	for _, group := range q.Must {
+		for _, t := range group {
+			arr = append(arr, strings.Fields(strings.ToLower(t.Text))...)
+		}
+	}
+	return arr
+}
+
+// setSnippets 后端没有生成标出匹配词的片段时，从原文中截取，正文中没有匹配词时从附件的文字中截取
+func setSnippets(tx *gorm.DB, arr []*model.Note, q *query.Query) {
+	words := snippetWords(q)
+	for _, v := range arr {
+		if strings.Contains(v.Snippet, model.HighlightStart) {
+			continue
+		}
+		if s := snippet(v.Content, words); s != "" {
+			v.Snippet = s
+			continue
+		}
+		if v.Attachment {
+			if s := snippet(attachmentText(tx, v.Content), words); s != "" {
+				v.Snippet = s
+			}
+		}
+	}
+}
+
+// filter 标签及日期的过滤条件，作用于 note 表
+func filter(tx *gorm.DB, q *query.Query) *gorm.DB {
+	for _, v := range q.Tags {
+		tx = tx.Where("note.id IN (?)", taggedNotes(tx, v))
+	}
+	for _, v := range q.NotTags {
+		tx = tx.Where("note.id NOT IN (?)", taggedNotes(tx, v))
+	}
+	if !q.Before.IsZero() {
+		tx = tx.Where("note.created_at < ?", q.Before)
+	}
+	if !q.After.IsZero() {
+		tx = tx.Where("note.created_at >= ?", q.After)
+	}
+	return tx
+}
+
+// filterSearch 没有搜索词时，只按标签及日期过滤
+func filterSearch(tx *gorm.DB, q *query.Query, offset, limit int) (arr []*model.Note, count int64) {
+	if !q.HasFilter() {
+		return
+	}
+	filter(tx.Model(&model.Note{}), q).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&arr)
+	filter(tx.Model(&model.Note{}), q).Count(&count)
+	return
+}
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string { return likeReplacer.Replace(s) }
+
+func pageNotes(arr []*model.Note, offset, limit int) []*model.Note {
+	if offset >= len(arr) {
+		return nil
+	}
+	if offset+limit < len(arr) {
+		arr = arr[:offset+limit]
+	}
+	return arr[offset:]
+}
+
+func pageHits(arr []hit, offset, limit int) []hit {
+	if offset >= len(arr) {
+		return nil
+	}
+	if offset+limit < len(arr) {
+		arr = arr[:offset+limit]
+	}
+	return arr[offset:]
+}
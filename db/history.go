@@ -0,0 +1,42 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/x2ox/memo/model"
+)
+
+// historyLimit 最多保留的搜索记录
+const historyLimit = 50
+
+type historySrv struct{}
+
+// Add 记录搜索内容，已存在时更新时间
+func (srv *historySrv) Add(q string) error {
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "query"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at"}),
+	}).Create(&model.History{Query: q, UpdatedAt: time.Now()}).Error; err != nil {
+		return err
+	}
+
+	// 删除比第 historyLimit 条更早的记录
+	var arr []time.Time
+	if err := db.Model(&model.History{}).Order("updated_at DESC").
+		Offset(historyLimit-1).Limit(1).Pluck("updated_at", &arr).Error; err != nil || len(arr) == 0 {
+		return err
+	}
+	return db.Where("updated_at < ?", arr[0]).Delete(&model.History{}).Error
+}
+
+// Recent 最近的搜索内容
+func (srv *historySrv) Recent(limit int) []string {
+	var arr []string
+	if err := db.Model(&model.History{}).Order("updated_at DESC").
+		Limit(limit).Pluck("query", &arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
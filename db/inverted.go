@@ -0,0 +1,300 @@
+package db
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/query"
+	"gorm.io/gorm"
+)
+
+// Inverted 内置的倒排索引，词频存放在 note_term 表，不依赖 FTS5 及 tsvector
+type Inverted struct{ db *gorm.DB }
+
+type noteTerm struct {
+	Term   string `gorm:"primaryKey"`
+	NoteID uint64 `gorm:"primaryKey;index"`
+	Field  uint8  `gorm:"primaryKey"`
+	Count  int
+}
+
+func (noteTerm) TableName() string { return "note_term" }
+
+const (
+	fieldTitle uint8 = iota
+	fieldContent
+	fieldAttachment
+	fieldCount
+)
+
+// fieldWeight 标题 > 正文 > 附件
+var fieldWeight = [fieldCount]float64{10, 5, 1}
+
+func (i Inverted) New(db *gorm.DB) FullTextSearch { return &Inverted{db: db} }
+func (i Inverted) Init() error                    { return i.db.AutoMigrate(&noteTerm{}) }
+func (i Inverted) Index() error                   { return nil }
+func (i Inverted) ReIndex(progress Progress) error {
+	return i.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&noteTerm{}).Error; err != nil {
+			return err
+		}
+		return rebuild(Inverted{db: tx}, tx, progress)
+	})
+}
+func (i Inverted) Clean() error { return i.db.Migrator().DropTable(&noteTerm{}) }
+
+func (i Inverted) Create(titleKeywords, contentKeywords, attachmentKeywords string, id uint64) error {
+	if err := i.Delete(id); err != nil {
+		return err
+	}
+
+	var arr []noteTerm
+	for field, keywords := range [fieldCount]string{titleKeywords, contentKeywords, attachmentKeywords} {
+		count := make(map[string]int)
+		for _, v := range strings.Fields(keywords) {
+			count[strings.ToLower(v)]++
+		}
+		for term, c := range count {
+			arr = append(arr, noteTerm{Term: term, NoteID: id, Field: uint8(field), Count: c})
+		}
+	}
+	if len(arr) == 0 {
+		return nil
+	}
+	return i.db.CreateInBatches(arr, 500).Error
+}
+
+func (i Inverted) Delete(id uint64) error {
+	return i.db.Where("note_id = ?", id).Delete(&noteTerm{}).Error
+}
+
+// Search 先按词筛选，再取出笔记验证短语及过滤条件，按 tf-idf 排序
+func (i Inverted) Search(q *query.Query, offset, limit int) (arr []*model.Note, count int64) {
+	if !q.HasText() {
+		return filterSearch(i.db, q, offset, limit)
+	}
+
+	p := i.postings(q)
+	var ids []uint64
+	for id := range p.candidates(q) {
+		if p.matches(q, id, nil) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var notes []*model.Note
+	if err := filter(i.db.Model(&model.Note{}).Where("id IN ?", ids), q).Find(&notes).Error; err != nil {
+		return
+	}
+
+	var (
+		total  int64
+		tokens = lowerTokens(q.Tokens())
+		scores = make(map[uint64]float64, len(notes))
+	)
+	i.db.Model(&model.Note{}).Count(&total)
+	for _, n := range notes {
+		if !p.matches(q, n.ID, n) {
+			continue
+		}
+		for _, v := range tokens {
+			f := p[v][n.ID]
+			if f == nil {
+				continue
+			}
+			idf := math.Log(1 + float64(total)/float64(len(p[v])))
+			for field := range f {
+				scores[n.ID] += fieldWeight[field] * float64(f[field]) * idf
+			}
+			if f[fieldAttachment] > 0 {
+				n.Attachment = true
+			}
+		}
+		arr = append(arr, n)
+	}
+
+	sort.Slice(arr, func(a, b int) bool {
+		if scores[arr[a].ID] != scores[arr[b].ID] {
+			return scores[arr[a].ID] > scores[arr[b].ID]
+		}
+		return arr[a].ID > arr[b].ID
+	})
+
+	count = int64(len(arr))
+	arr = pageNotes(arr, offset, limit)
+	setSnippets(i.db, arr, q)
+	return
+}
+
+// postings 词 -> 笔记 -> 各字段中出现的次数
+type postings map[string]map[uint64]*[fieldCount]int
+
+func (i Inverted) postings(q *query.Query) postings {
+	var terms []string
+	for _, group := range q.Must {
+		for _, t := range group {
+			terms = append(terms, lowerTokens(t.Tokens)...)
+		}
+	}
+	for _, t := range q.Not {
+		terms = append(terms, lowerTokens(t.Tokens)...)
+	}
+
+	var arr []noteTerm
+	p := make(postings)
+	if err := i.db.Model(&noteTerm{}).Where("term IN ?", terms).Find(&arr).Error; err != nil {
+		return p
+	}
+	for _, v := range arr {
+		if p[v.Term] == nil {
+			p[v.Term] = make(map[uint64]*[fieldCount]int)
+		}
+		if p[v.Term][v.NoteID] == nil {
+			p[v.Term][v.NoteID] = &[fieldCount]int{}
+		}
+		if v.Field < fieldCount {
+			p[v.Term][v.NoteID][v.Field] += v.Count
+		}
+	}
+	return p
+}
+
+// candidates 包含第一组中任意词的笔记
+func (p postings) candidates(q *query.Query) map[uint64]struct{} {
+	m := make(map[uint64]struct{})
+	for _, t := range q.Must[0] {
+		for _, v := range lowerTokens(t.Tokens) {
+			for id := range p[v] {
+				m[id] = struct{}{}
+			}
+		}
+	}
+	return m
+}
+
+// matches n 为空时不验证短语，只按词判断
+func (p postings) matches(q *query.Query, id uint64, n *model.Note) bool {
+	for _, group := range q.Must {
+		ok := false
+		for _, t := range group {
+			if p.has(t, id) && (n == nil || !t.Phrase || contains(t, n)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, t := range q.Not {
+		if p.has(t, id) && (!t.Phrase || (n != nil && contains(t, n))) {
+			return false
+		}
+	}
+	return true
+}
+
+// has 笔记包含词的所有分词，限定标题时只看标题
+func (p postings) has(t query.Term, id uint64) bool {
+	for _, v := range lowerTokens(t.Tokens) {
+		f := p[v][id]
+		if f == nil || (t.Field == query.FieldTitle && f[fieldTitle] == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains 短语是否原样出现在标题或正文中
+func contains(t query.Term, n *model.Note) bool {
+	text := strings.ToLower(t.Text)
+	if t.Field == query.FieldTitle {
+		return strings.Contains(strings.ToLower(n.Title), text)
+	}
+	return strings.Contains(strings.ToLower(n.Title), text) ||
+		strings.Contains(strings.ToLower(n.Content), text)
+}
+
+func lowerTokens(arr []string) []string {
+	tokens := make([]string, 0, len(arr))
+	for _, v := range arr {
+		tokens = append(tokens, strings.ToLower(v))
+	}
+	return tokens
+}
+
+// snippet 截取第一个匹配词附近的内容，匹配词由 HighlightStart 及 HighlightEnd 包围
+func snippet(text string, tokens []string) string {
+	var (
+		rs    = []rune(text)
+		lower = make([]rune, len(rs))
+		words = make([][]rune, 0, len(tokens))
+	)
+	for i, r := range rs {
+		lower[i] = unicode.ToLower(r)
+	}
+	for _, v := range tokens {
+		if v != "" {
+			words = append(words, []rune(v))
+		}
+	}
+
+	// 每个位置上最长的匹配词
+	matchAt := func(i int) int {
+		n := 0
+		for _, w := range words {
+			if len(w) > n && i+len(w) <= len(lower) && string(lower[i:i+len(w)]) == string(w) {
+				n = len(w)
+			}
+		}
+		return n
+	}
+
+	first := -1
+	for i := range lower {
+		if matchAt(i) > 0 {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start, end := first-20, first+60
+	if start < 0 {
+		start = 0
+	}
+	if end > len(rs) {
+		end = len(rs)
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	for i := start; i < end; {
+		if n := matchAt(i); n > 0 {
+			if i+n > end {
+				n = end - i
+			}
+			sb.WriteString(model.HighlightStart)
+			sb.WriteString(string(rs[i : i+n]))
+			sb.WriteString(model.HighlightEnd)
+			i += n
+			continue
+		}
+		sb.WriteRune(rs[i])
+		i++
+	}
+	if end < len(rs) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
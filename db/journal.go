@@ -0,0 +1,72 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/x2ox/memo/model"
+)
+
+type journalSrv struct {
+	mux sync.Mutex
+}
+
+// Get 某一天的日记，没有时返回 nil。只查找日记模式创建的笔记，标题相同的其他笔记不是日记
+func (srv *journalSrv) Get(day time.Time) *model.Note {
+	var n model.Note
+	if db.Model(&model.Note{}).Where("journal = ?", day.Format(model.JournalLayout)).
+		Order("id DESC").Limit(1).Find(&n).RowsAffected == 0 {
+		return nil
+	}
+	return &n
+}
+
+// Append 追加到 now 当天的日记，当天第一次使用时创建
+func (srv *journalSrv) Append(now time.Time, text string) (*model.Note, error) {
+	srv.mux.Lock()
+	defer srv.mux.Unlock()
+
+	entry := model.JournalEntry(now, text)
+	note := srv.Get(now)
+	err := transaction(func(tx *gorm.DB) error {
+		if note != nil {
+			note.Content = strings.TrimRight(note.Content, "\n") + "\n\n" + entry + "\n"
+			return updateNote(tx, note)
+		}
+
+		note = &model.Note{Title: model.JournalTitle(now), Content: entry + "\n", Journal: now.Format(model.JournalLayout)}
+		note.ParseMeta()
+		if err := tx.Create(note).Error; err != nil {
+			return err
+		}
+		if err := createIndex(tx, note); err != nil {
+			return err
+		}
+		return linkNote(tx, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Days 某个月有日记的日期
+func (srv *journalSrv) Days(year int, month time.Month) map[int]bool {
+	var (
+		arr    []string
+		prefix = time.Date(year, month, 1, 0, 0, 0, 0, model.Conf.Location()).Format("2006-01-")
+	)
+	db.Model(&model.Note{}).Where("journal LIKE ?", prefix+"%").Pluck("journal", &arr)
+
+	days := make(map[int]bool, len(arr))
+	for _, v := range arr {
+		if d, err := strconv.Atoi(v[len(prefix) : len(prefix)+2]); err == nil {
+			days[d] = true
+		}
+	}
+	return days
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"sort"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/x2ox/memo/model"
+)
+
+// resolveLinks 一次查出全部的引用，[[#ID]] 按 ID 查找，[[标题]] 按标题查找，同名时使用最新的
+func resolveLinks(tx *gorm.DB, targets []string) map[string]*model.Note {
+	m := make(map[string]*model.Note, len(targets))
+	var (
+		ids    []uint64
+		titles []string
+	)
+	for _, v := range targets {
+		if id, ok := model.WikiLinkID(v); ok {
+			ids = append(ids, id)
+		} else {
+			titles = append(titles, v)
+		}
+	}
+	if len(ids) == 0 && len(titles) == 0 {
+		return m
+	}
+
+	var arr []*model.Note
+	if err := tx.Model(&model.Note{}).Select("id", "title").
+		Where("id IN ? OR title IN ?", ids, titles).
+		Order("id").Find(&arr).Error; err != nil {
+		return m
+	}
+	byID := make(map[uint64]*model.Note, len(arr))
+	byTitle := make(map[string]*model.Note, len(arr))
+	for _, n := range arr {
+		byID[n.ID], byTitle[n.Title] = n, n
+	}
+	for _, v := range targets {
+		var n *model.Note
+		if id, ok := model.WikiLinkID(v); ok {
+			n = byID[id]
+		} else {
+			n = byTitle[v]
+		}
+		if n != nil {
+			m[v] = n
+		}
+	}
+	return m
+}
+
+// linkNote 保存笔记引用的目标，标题区分大小写，与渲染时的解析一致
+func linkNote(tx *gorm.DB, note *model.Note) error {
+	if err := unlinkNote(tx, note.ID); err != nil {
+		return err
+	}
+
+	var arr []model.NoteLink
+	for _, v := range note.WikiLinks() {
+		arr = append(arr, model.NoteLink{FromID: note.ID, Target: model.WikiLinkTarget(v)})
+	}
+	if len(arr) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&arr).Error
+}
+
+// unlinkNote 删除笔记中的引用，引用了该笔记的引用在渲染时不再解析到它
+func unlinkNote(tx *gorm.DB, id uint64) error {
+	return tx.Where("from_id = ?", id).Delete(&model.NoteLink{}).Error
+}
+
+// Resolve 解析渲染时需要的引用及反向引用，按标题引用时只有同名中最新的笔记才有反向引用
+func (srv *noteSrv) Resolve(note *model.Note) {
+	note.Links = resolveLinks(db, note.WikiLinks())
+
+	targets := []string{"#" + strconv.FormatUint(note.ID, 10)}
+	var newer int64
+	db.Model(&model.Note{}).Where("title = ? AND id > ?", note.Title, note.ID).Count(&newer)
+	if newer == 0 {
+		targets = append(targets, note.Title)
+	}
+	db.Model(&model.Note{}).
+		Where("id IN (?)", db.Model(&model.NoteLink{}).Select("from_id").Where("target IN ?", targets)).
+		Where("id <> ?", note.ID).
+		Order("id DESC").
+		Find(&note.Backlinks)
+}
+
+// Update 修改标题及内容，重建索引及引用
+func (srv *noteSrv) Update(note *model.Note) error {
+	return transaction(func(tx *gorm.DB) error { return updateNote(tx, note) })
+}
+
+func updateNote(tx *gorm.DB, note *model.Note) error {
+	note.ParseMeta()
+	if err := tx.Model(note).Select("title", "content", "notebook", "meta", "todo").Updates(note).Error; err != nil {
+		return err
+	}
+	if err := Search.New(tx).Delete(note.ID); err != nil {
+		return err
+	}
+	if err := createIndex(tx, note); err != nil {
+		return err
+	}
+	return linkNote(tx, note)
+}
+
+// Graph 有引用关系的笔记，节点按 ID 排序，URL 由调用方填写
+func (srv *noteSrv) Graph() *model.LinkGraph {
+	g := &model.LinkGraph{Nodes: []model.GraphNode{}, Links: []model.GraphEdge{}}
+	var rows []model.NoteLink
+	if err := db.Model(&model.NoteLink{}).Find(&rows).Error; err != nil {
+		return g
+	}
+
+	targets := make([]string, 0, len(rows))
+	for _, v := range rows {
+		targets = append(targets, v.Target)
+	}
+	to := resolveLinks(db, targets)
+
+	var (
+		seen      = make(map[model.GraphEdge]bool)
+		backlinks = make(map[uint64]int)
+		ids       = make([]uint64, 0, len(rows)*2)
+	)
+	for _, v := range rows {
+		n, ok := to[v.Target]
+		e := model.GraphEdge{FromID: v.FromID}
+		if ok {
+			e.ToID = n.ID
+		}
+		if !ok || e.ToID == e.FromID || seen[e] {
+			continue
+		}
+		seen[e] = true
+		g.Links = append(g.Links, e)
+		backlinks[e.ToID]++
+		ids = append(ids, e.FromID, e.ToID)
+	}
+	if len(ids) == 0 {
+		return g
+	}
+	sort.Slice(g.Links, func(i, j int) bool {
+		if g.Links[i].FromID != g.Links[j].FromID {
+			return g.Links[i].FromID < g.Links[j].FromID
+		}
+		return g.Links[i].ToID < g.Links[j].ToID
+	})
+
+	var arr []*model.Note
+	db.Model(&model.Note{}).Select("id", "title").Where("id IN ?", ids).Order("id").Find(&arr)
+	for _, n := range arr {
+		g.Nodes = append(g.Nodes, model.GraphNode{
+			ID:        n.ID,
+			Title:     n.Title,
+			Backlinks: backlinks[n.ID],
+		})
+	}
+	return g
+}
@@ -1,7 +1,11 @@
 package db
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/query"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -12,22 +16,78 @@ func initPostgreSQL() gorm.Dialector {
 
 type PostgreSQL struct{ db *gorm.DB }
 
-func (p PostgreSQL) Search(keywords string, offset, limit int) (arr []*model.Note, count int64) {
-	p.db.Model(&model.Note{}).Where("id IN (?)", db.
-		Table("note_row, to_tsquery( 'simple', ? ) query", "["+keywords+"]").
-		Select("id").
-		Where("note_row.tsv_content @@query").
+// Search 附件的权重为 C，低于标题 A 及正文 B
+func (p PostgreSQL) Search(q *query.Query, offset, limit int) (arr []*model.Note, count int64) {
+	if !q.HasText() {
+		return filterSearch(p.db, q, offset, limit)
+	}
+
+	tsquery := p.tsquery(q)
+	var hits []hit
+	p.table(q, tsquery).
+		Select("note_row.id AS id, "+
+			"ts_filter( note_row.tsv_content, '{c}' ) @@query AS attachment, "+
+			"ts_headline( 'simple', note.content, query, ? ) AS snippet", headlineOptions).
 		Order("ts_rank( note_row.tsv_content, query ) DESC").
 		Offset(offset).
-		Limit(limit),
-	).Find(&arr)
-	p.db.Model(&model.Note{}).Where("id IN (?)", p.db.
-		Table("note_row, to_tsquery( 'simple', ? ) query", "["+keywords+"]").
-		Select("id").
-		Where("note_row.tsv_content @@query")).Count(&count)
+		Limit(limit).
+		Scan(&hits)
+	arr = hitNotes(p.db, hits)
+	p.table(q, tsquery).Count(&count)
+
+	// ts_headline 按 simple 配置切分原文，标不出中文的词，也不包括附件
+	setSnippets(p.db, arr, q)
 	return
 }
 
+var headlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=24, MinWords=8, `+
+	`MaxFragments=2, FragmentDelimiter=" … "`, model.HighlightStart, model.HighlightEnd)
+
+func (p PostgreSQL) table(q *query.Query, tsquery string) *gorm.DB {
+	return filter(p.db.Table("note_row, note, to_tsquery( 'simple', ? ) query", tsquery).
+		Where("note.id = note_row.id AND note.deleted_at IS NULL").
+		Where("note_row.tsv_content @@query"), q)
+}
+
+// tsquery 编译为 tsquery，标题使用权重 A 限定
+func (PostgreSQL) tsquery(q *query.Query) string {
+	groups := make([]string, 0, len(q.Must))
+	for _, group := range q.Must {
+		arr := make([]string, 0, len(group))
+		for _, t := range group {
+			arr = append(arr, tsTerm(t))
+		}
+		groups = append(groups, "("+strings.Join(arr, " | ")+")")
+	}
+	for _, t := range q.Not {
+		groups = append(groups, "!"+tsTerm(t))
+	}
+	return strings.Join(groups, " & ")
+}
+
+func tsTerm(t query.Term) string {
+	weight, sep := "", " & "
+	if t.Field == query.FieldTitle {
+		weight = ":A"
+	}
+	if t.Phrase {
+		sep = " <-> "
+	}
+
+	arr := make([]string, 0, len(t.Tokens))
+	for _, v := range t.Tokens {
+		arr = append(arr, tsLexeme(v)+weight)
+	}
+	if t.Prefix {
+		arr[len(arr)-1] = tsLexeme(t.Tokens[len(t.Tokens)-1]) + ":*" + strings.TrimPrefix(weight, ":")
+	}
+	return "(" + strings.Join(arr, sep) + ")"
+}
+
+func tsLexeme(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", "''") + "'"
+}
+
 func (p PostgreSQL) Init() error {
 	var count int64
 	if err := p.db.Table("pg_class").Where("relname = ?", "note_row").Count(&count).Error; err != nil {
@@ -48,19 +108,34 @@ func (p PostgreSQL) Index() error {
 	}
 	return nil
 }
-func (p PostgreSQL) ReIndex() error {
-	return p.db.Exec("REINDEX INDEX tsv_content_idx;").Error
+
+// ReIndex 在事务中删除并重建 note_row 及 GIN 索引
+func (p PostgreSQL) ReIndex(progress Progress) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DROP TABLE IF EXISTS note_row").Error; err != nil {
+			return err
+		}
+		n := PostgreSQL{db: tx}
+		if err := n.Init(); err != nil {
+			return err
+		}
+		if err := rebuild(n, tx, progress); err != nil {
+			return err
+		}
+		return n.Index()
+	})
 }
 func (p PostgreSQL) Clean() error {
 	return p.db.Exec("DROP TABLE note_row").Error
 }
 
-func (p PostgreSQL) Create(titleKeywords, contentKeywords string, id uint64) error {
+func (p PostgreSQL) Create(titleKeywords, contentKeywords, attachmentKeywords string, id uint64) error {
 	return p.db.Exec(`INSERT INTO "note_row" VALUES( ?, 
 setweight( to_tsvector( 'simple', ? ), 'A' ) || 
-setweight( to_tsvector( 'simple', ? ), 'B' ) )
+setweight( to_tsvector( 'simple', ? ), 'B' ) || 
+setweight( to_tsvector( 'simple', ? ), 'C' ) )
 ON CONFLICT ("id") DO UPDATE SET "tsv_content"="excluded"."tsv_content"`,
-		id, titleKeywords, contentKeywords).Error
+		id, titleKeywords, contentKeywords, attachmentKeywords).Error
 }
 func (p PostgreSQL) Delete(id uint64) error {
 	return p.db.Table("note_row").Delete(id).Error
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"time"
+
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/when"
+)
+
+type reminderSrv struct{}
+
+// Add 为笔记添加提醒
+func (srv *reminderSrv) Add(noteID uint64, rule when.Rule) (*model.Reminder, error) {
+	if rule.Unit == when.Month && rule.Day == 0 {
+		rule.Day = rule.At.In(model.Conf.Location()).Day()
+	}
+	r := &model.Reminder{NoteID: noteID, At: rule.At, Repeat: rule.Unit, Every: rule.Every, Day: rule.Day}
+	if err := db.Create(r).Error; err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (srv *reminderSrv) GetWithID(id uint64) *model.Reminder {
+	var r model.Reminder
+	if db.Model(&model.Reminder{}).Where("id = ?", id).Limit(1).Find(&r).RowsAffected == 0 {
+		return nil
+	}
+	return &r
+}
+
+// Due 到时间且还未发送的提醒
+func (srv *reminderSrv) Due(now time.Time) []*model.Reminder {
+	var arr []*model.Reminder
+	if err := db.Model(&model.Reminder{}).Where("fired = ? AND at <= ?", false, now).
+		Order("at").Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+// Upcoming 还未发送的提醒，最近的在前，limit 为 0 时不限制
+func (srv *reminderSrv) Upcoming(limit int) []*model.Reminder {
+	var arr []*model.Reminder
+	tx := db.Model(&model.Reminder{}).Where("fired = ?", false).Order("at")
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+	if err := tx.Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+// List 全部提醒，已发送但还未完成的一次性提醒时间较早，排在前面，limit 为 0 时不限制
+func (srv *reminderSrv) List(limit int) []*model.Reminder {
+	var arr []*model.Reminder
+	tx := db.Model(&model.Reminder{}).Order("at")
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+	if err := tx.Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+// Fire 提醒已发送，重复的提醒移到下一次，否则等待推迟或完成
+func (srv *reminderSrv) Fire(r *model.Reminder, now time.Time) error {
+	if r.IsRepeat() {
+		r.At = r.Rule().Next(now)
+	} else {
+		r.Fired = true
+	}
+	return db.Model(r).Select("at", "fired", "updated_at").Updates(r).Error
+}
+
+// Snooze 推迟到 at 再次提醒，重复的提醒另外添加一次性的提醒，不影响原有的规则
+func (srv *reminderSrv) Snooze(id uint64, at time.Time) (*model.Reminder, error) {
+	r := srv.GetWithID(id)
+	if r == nil {
+		return nil, nil
+	}
+	if r.IsRepeat() {
+		return srv.Add(r.NoteID, when.Rule{At: at})
+	}
+	r.At, r.Fired = at, false
+	return r, db.Model(r).Select("at", "fired", "updated_at").Updates(r).Error
+}
+
+// Done 完成提醒，不存在时返回 nil。一次性的提醒会被删除，重复的提醒在发送时已经移到下一次，不做修改
+func (srv *reminderSrv) Done(id uint64) (*model.Reminder, error) {
+	r := srv.GetWithID(id)
+	if r == nil || r.IsRepeat() {
+		return r, nil
+	}
+	return r, db.Delete(r).Error
+}
+
+// Cancel 取消提醒，不存在时返回 false
+func (srv *reminderSrv) Cancel(id uint64) (bool, error) {
+	tx := db.Where("id = ?", id).Delete(&model.Reminder{})
+	return tx.RowsAffected != 0, tx.Error
+}
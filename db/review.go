@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/x2ox/memo/model"
+)
+
+// ErrNoReview 笔记不在复习队列中
+var ErrNoReview = errors.New("review not found")
+
+type reviewSrv struct{}
+
+// Add 把笔记加入复习队列，已经在队列中时返回 false
+func (srv *reviewSrv) Add(noteID uint64, now time.Time) (bool, error) {
+	if srv.GetWithNoteID(noteID) != nil {
+		return false, nil
+	}
+	if err := db.Create(model.NewReview(noteID, now)).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Remove 从复习队列中移除，不在队列中时返回 false
+func (srv *reviewSrv) Remove(noteID uint64) (bool, error) {
+	tx := db.Where("note_id = ?", noteID).Delete(&model.Review{})
+	return tx.RowsAffected != 0, tx.Error
+}
+
+func (srv *reviewSrv) GetWithNoteID(noteID uint64) *model.Review {
+	var r model.Review
+	if db.Model(&model.Review{}).Where("note_id = ?", noteID).Limit(1).Find(&r).RowsAffected == 0 {
+		return nil
+	}
+	return &r
+}
+
+// Due 到期需要复习的笔记，最早到期的在前，limit 为 0 时不限制
+func (srv *reviewSrv) Due(now time.Time, limit int) []*model.Review {
+	var arr []*model.Review
+	tx := db.Model(&model.Review{}).Where("due <= ?", now).Order("due")
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+	if err := tx.Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+// DueCount 到期需要复习的数量
+func (srv *reviewSrv) DueCount(now time.Time) int64 {
+	var count int64
+	db.Model(&model.Review{}).Where("due <= ?", now).Count(&count)
+	return count
+}
+
+// Grade 记录复习的评分，安排下一次复习
+func (srv *reviewSrv) Grade(noteID uint64, g model.Grade, now time.Time) (*model.Review, error) {
+	var r model.Review
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if tx.Model(&model.Review{}).Where("note_id = ?", noteID).Limit(1).Find(&r).RowsAffected == 0 {
+			return ErrNoReview
+		}
+		r.Schedule(g, now)
+		return tx.Save(&r).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"gorm.io/gorm/clause"
+
+	"github.com/x2ox/memo/model"
+)
+
+type scheduleSrv struct{}
+
+// Last 定时任务最近一次执行的日期，没有执行过时为空
+func (srv *scheduleSrv) Last(name string) string {
+	var run model.ScheduleRun
+	if db.Model(&model.ScheduleRun{}).Where("name = ?", name).Limit(1).Find(&run).RowsAffected == 0 {
+		return ""
+	}
+	return run.Last
+}
+
+// SetLast 记录定时任务执行的日期
+func (srv *scheduleSrv) SetLast(name, day string) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last", "updated_at"}),
+	}).Create(&model.ScheduleRun{Name: name, Last: day}).Error
+}
@@ -1,7 +1,13 @@
 package db
 
 import (
+	"strings"
+	"time"
+
 	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/query"
+	"go.uber.org/zap"
+	"go.x2ox.com/blackdatura"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -13,37 +19,132 @@ func initSQLite() gorm.Dialector {
 type SQLite struct{ db *gorm.DB }
 
 func (s SQLite) Init() error {
-	var count int64
+	var arr []string
 	if err := s.db.Table("sqlite_master").Where("type = 'table'").
-		Where("name = ?", "note_row").Count(&count).Error; err != nil {
+		Where("name = ?", "note_row").Pluck("sql", &arr).Error; err != nil {
 		return err
 	}
-	if count == 0 {
-		return s.db.Exec(`CREATE VIRTUAL TABLE note_row USING fts5(id UNINDEXED, title, content)`).Error
+	if len(arr) == 0 {
+		return s.create()
+	}
+	if strings.Contains(arr[0], "attachment") {
+		return nil
+	}
+
+	// 旧版本的索引没有 attachment 列，虚拟表无法添加列，只能重建，笔记较多时需要等待一段时间
+	log := blackdatura.With("search")
+	var total int64
+	s.db.Model(&model.Note{}).Count(&total)
+	log.Warn("full text index is outdated, rebuilding note_row before start", zap.Int64("notes", total))
+	start := time.Now()
+	if err := s.ReIndex(func(done, total int64) {
+		log.Info("rebuilding note_row", zap.Int64("done", done), zap.Int64("total", total))
+	}); err != nil {
+		return err
 	}
+	log.Warn("note_row rebuilt", zap.Duration("elapsed", time.Since(start)))
 	return nil
 }
 
-func (s SQLite) Index() error   { return nil }
-func (s SQLite) ReIndex() error { return nil }
-func (s SQLite) Clean() error   { return s.db.Exec("DROP TABLE note_row").Error }
+func (s SQLite) create() error {
+	return s.db.Exec(`CREATE VIRTUAL TABLE note_row USING fts5(id UNINDEXED, title, content, attachment)`).Error
+}
+
+func (s SQLite) Index() error { return nil }
 
-func (s SQLite) Search(keywords string, offset, limit int) (arr []*model.Note, count int64) {
-	s.db.Model(&model.Note{}).Where("id IN (?)", db.
-		Select("id").Table("note_row").
-		Where("note_row MATCH ?", keywords).
-		Order("rank").
+// ReIndex 在事务中删除并重建 note_row。数据库使用共享缓存，整个重建过程都持有写锁，
+// 期间搜索会返回 SQLITE_LOCKED 而不是旧的结果
+func (s SQLite) ReIndex(progress Progress) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DROP TABLE IF EXISTS note_row").Error; err != nil {
+			return err
+		}
+		n := SQLite{db: tx}
+		if err := n.create(); err != nil {
+			return err
+		}
+		return rebuild(n, tx, progress)
+	})
+}
+func (s SQLite) Clean() error {
+	return s.db.Exec("DROP TABLE note_row").Error
+}
+
+// Search 按 bm25 排序，权重 标题 > 正文 > 附件
+func (s SQLite) Search(q *query.Query, offset, limit int) (arr []*model.Note, count int64) {
+	if !q.HasText() {
+		return filterSearch(s.db, q, offset, limit)
+	}
+
+	match := s.match(q)
+	var hits []hit
+	s.table(q, match).
+		Select("note_row.id AS id, " +
+			"instr(highlight(note_row, 3, char(1), char(2)), char(1)) > 0 AS attachment").
+		Order("bm25(note_row, 0, 10, 5, 1)").
 		Offset(offset).
-		Limit(limit),
-	).Find(&arr)
-	s.db.Model(&model.Note{}).Where("id IN (?)", db.
-		Select("id").Table("note_row").
-		Where("note_row MATCH ?", keywords)).Count(&count)
+		Limit(limit).
+		Scan(&hits)
+	arr = hitNotes(s.db, hits)
+	s.table(q, match).Count(&count)
+
+	// note_row 中是分词后的结果，snippet() 只能截取到以空格分隔的词，片段从原文中截取
+	setSnippets(s.db, arr, q)
 	return
 }
-func (s SQLite) Create(titleKeywords, contentKeywords string, id uint64) error {
-	return s.db.Exec(`INSERT INTO "note_row"("id", "title", "content") VALUES (?, ?, ?)`,
-		id, titleKeywords, contentKeywords).Error
+
+func (s SQLite) table(q *query.Query, match string) *gorm.DB {
+	return filter(s.db.Table("note_row, note").
+		Where("note.id = note_row.id AND note.deleted_at IS NULL").
+		Where("note_row MATCH ?", match), q)
+}
+
+// match 编译为 FTS5 的查询语句，每个词都作为字符串，避免标点引起的语法错误
+func (SQLite) match(q *query.Query) string {
+	groups := make([]string, 0, len(q.Must))
+	for _, group := range q.Must {
+		arr := make([]string, 0, len(group))
+		for _, t := range group {
+			arr = append(arr, fts5Term(t))
+		}
+		groups = append(groups, "("+strings.Join(arr, " OR ")+")")
+	}
+	s := strings.Join(groups, " AND ")
+
+	if len(q.Not) > 0 {
+		arr := make([]string, 0, len(q.Not))
+		for _, t := range q.Not {
+			arr = append(arr, fts5Term(t))
+		}
+		s = "(" + s + ") NOT (" + strings.Join(arr, " OR ") + ")"
+	}
+	return s
+}
+
+func fts5Term(t query.Term) string {
+	column := ""
+	if t.Field == query.FieldTitle {
+		column = "title : "
+	}
+	if t.Phrase {
+		return column + fts5String(strings.Join(t.Tokens, " "))
+	}
+
+	arr := make([]string, 0, len(t.Tokens))
+	for _, v := range t.Tokens {
+		arr = append(arr, column+fts5String(v))
+	}
+	if t.Prefix {
+		arr[len(arr)-1] += " *"
+	}
+	return "(" + strings.Join(arr, " AND ") + ")"
+}
+
+func fts5String(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` }
+
+func (s SQLite) Create(titleKeywords, contentKeywords, attachmentKeywords string, id uint64) error {
+	return s.db.Exec(`INSERT INTO "note_row"("id", "title", "content", "attachment") VALUES (?, ?, ?, ?)`,
+		id, titleKeywords, contentKeywords, attachmentKeywords).Error
 }
 func (s SQLite) Delete(id uint64) error {
 	return s.db.Exec("DELETE FROM `note_row` WHERE `note_row`.id = ?", id).Error
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/x2ox/memo/model"
+)
+
+// noteTag 笔记的标签，转为小写，按标签过滤时整个匹配
+type noteTag struct {
+	NoteID uint64 `gorm:"primaryKey"`
+	Tag    string `gorm:"primaryKey;index"`
+}
+
+func (noteTag) TableName() string { return "note_tag" }
+
+// initTags 第一次创建标签表时，从已有的笔记中填充
+func initTags() error {
+	exists := db.Migrator().HasTable(&noteTag{})
+	if err := db.AutoMigrate(&noteTag{}); err != nil || exists {
+		return err
+	}
+
+	var arr []*model.Note
+	return db.Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&model.Note{}).FindInBatches(&arr, rebuildBatch, func(*gorm.DB, int) error {
+			for _, v := range arr {
+				if err := saveTags(tx, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// saveTags 替换笔记的标签
+func saveTags(tx *gorm.DB, note *model.Note) error {
+	if err := tx.Where("note_id = ?", note.ID).Delete(&noteTag{}).Error; err != nil {
+		return err
+	}
+
+	var (
+		arr  []noteTag
+		seen = make(map[string]bool)
+	)
+	for _, v := range note.Tags() {
+		if v = strings.ToLower(v); !seen[v] {
+			seen[v] = true
+			arr = append(arr, noteTag{NoteID: note.ID, Tag: v})
+		}
+	}
+	if len(arr) == 0 {
+		return nil
+	}
+	return tx.Create(&arr).Error
+}
+
+// taggedNotes 有该标签的笔记 ID 的子查询
+func taggedNotes(tx *gorm.DB, tag string) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&noteTag{}).
+		Select("note_id").Where("tag = ?", strings.ToLower(tag))
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/x2ox/memo/model"
+)
+
+// ErrNoTask 笔记或任务不存在
+var ErrNoTask = errors.New("task not found")
+
+// initTodo 新增 todo 列时，统计已有笔记中未完成的任务
+func initTodo() error {
+	var arr []*model.Note
+	return db.Model(&model.Note{}).Where("content LIKE ?", "%[ ]%").
+		FindInBatches(&arr, rebuildBatch, func(*gorm.DB, int) error {
+			for _, v := range arr {
+				v.ParseMeta()
+				if err := db.Model(v).UpdateColumn("todo", v.Todo).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// OpenTasks 有未完成任务的笔记，最新的在前
+func (srv *noteSrv) OpenTasks() []*model.Note {
+	var arr []*model.Note
+	if err := db.Model(&model.Note{}).Where("todo > 0").
+		Order("id DESC").Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+// SetTask 修改笔记中位于 offset 的任务的完成状态，重建索引
+func (srv *noteSrv) SetTask(id uint64, offset int, done bool) (*model.Note, error) {
+	var note model.Note
+	err := transaction(func(tx *gorm.DB) error {
+		if tx.Model(&model.Note{}).Where("id = ?", id).Limit(1).Find(&note).RowsAffected == 0 {
+			return ErrNoTask
+		}
+		content := note.Content
+		if !note.SetTask(offset, done) {
+			return ErrNoTask
+		}
+		if content == note.Content {
+			return nil
+		}
+		return updateNote(tx, &note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/x2ox/memo/model"
+)
+
+type templateSrv struct{}
+
+// Save 保存模板，同名时覆盖内容
+func (srv *templateSrv) Save(name, content string) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "updated_at"}),
+	}).Create(&model.NoteTemplate{Name: name, Content: content}).Error
+}
+
+func (srv *templateSrv) List() []*model.NoteTemplate {
+	var arr []*model.NoteTemplate
+	if err := db.Model(&model.NoteTemplate{}).Order("name").Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+func (srv *templateSrv) GetWithID(id uint64) *model.NoteTemplate {
+	var t model.NoteTemplate
+	if db.Model(&model.NoteTemplate{}).Where("id = ?", id).Limit(1).Find(&t).RowsAffected == 0 {
+		return nil
+	}
+	return &t
+}
+
+func (srv *templateSrv) GetWithName(name string) *model.NoteTemplate {
+	var t model.NoteTemplate
+	if db.Model(&model.NoteTemplate{}).Where("name = ?", name).Limit(1).Find(&t).RowsAffected == 0 {
+		return nil
+	}
+	return &t
+}
+
+// Remove 删除模板，不存在时返回 false
+func (srv *templateSrv) Remove(name string) (bool, error) {
+	tx := db.Where("name = ?", name).Delete(&model.NoteTemplate{})
+	return tx.RowsAffected != 0, tx.Error
+}
+
+// Use 展开模板后放入草稿箱，以负的消息 ID 保存，编辑消息时不会修改到它。
+// 重复点击同一条消息中的按钮时不再添加，返回 false
+func (srv *templateSrv) Use(t *model.NoteTemplate, messageID int) (bool, error) {
+	content := t.Expand(time.Now())
+	if content == "" {
+		return false, nil
+	}
+	if content[len(content)-1] != '\n' {
+		content += "\n"
+	}
+
+	Input.mux.Lock()
+	defer Input.mux.Unlock()
+	var count int64
+	if err := db.Model(&model.Input{}).Where("message_id = ? AND content = ?", -messageID, content).
+		Count(&count).Error; err != nil || count != 0 {
+		return false, err
+	}
+	return true, db.Create(&model.Input{MessageID: -messageID, Content: content}).Error
+}
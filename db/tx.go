@@ -0,0 +1,40 @@
+package db
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const afterCommitKey = "memo:after_commit"
+
+// commitHooks 事务提交后执行的操作
+type commitHooks struct {
+	mux sync.Mutex
+	fns []func()
+}
+
+// transaction 执行事务，提交后依次执行通过 afterCommit 注册的操作，回滚时丢弃
+func transaction(fn func(tx *gorm.DB) error) error {
+	hooks := &commitHooks{}
+	if err := db.Set(afterCommitKey, hooks).Transaction(fn); err != nil {
+		return err
+	}
+	for _, f := range hooks.fns {
+		f()
+	}
+	return nil
+}
+
+// afterCommit 在 tx 所在的事务提交后执行 f，不在 transaction 中时立即执行
+func afterCommit(tx *gorm.DB, f func()) {
+	v, ok := tx.Get(afterCommitKey)
+	if !ok {
+		f()
+		return
+	}
+	hooks := v.(*commitHooks)
+	hooks.mux.Lock()
+	hooks.fns = append(hooks.fns, f)
+	hooks.mux.Unlock()
+}
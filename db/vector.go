@@ -0,0 +1,277 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/embedding"
+	"github.com/x2ox/memo/pkg/participle"
+	"github.com/x2ox/memo/pkg/query"
+)
+
+// noteVector 笔记的向量，以 float32 小端序保存，使用 pgvector 时另存一列 embedding
+type noteVector struct {
+	NoteID uint64 `gorm:"primaryKey"`
+	Vector []byte
+}
+
+func (noteVector) TableName() string { return "note_vector" }
+
+type vectorSrv struct{}
+
+// ErrDimension 向量的维度与配置不同
+var ErrDimension = errors.New("embedding dimension does not match the config")
+
+// initVector 维度不同的向量无法比较，删除后由后台重新计算
+func initVector() error {
+	if err := db.AutoMigrate(&noteVector{}); err != nil {
+		return err
+	}
+	dim := model.Conf.Embedding.Dimension
+	if dim > 0 {
+		if err := db.Where("length(vector) <> ?", 4*dim).Delete(&noteVector{}).Error; err != nil {
+			return err
+		}
+	}
+	if !model.Conf.IsPGVector() {
+		return nil
+	}
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+		return err
+	}
+	return initPGVector(dim)
+}
+
+// initPGVector embedding 列使用配置的维度并建立 HNSW 索引。维度改变时重建该列，已有的向量全部重新计算
+func initPGVector(dim int) error {
+	var arr []int
+	if err := db.Table("pg_attribute").
+		Where("attrelid = 'note_vector'::regclass AND attname = 'embedding' AND NOT attisdropped").
+		Pluck("atttypmod", &arr).Error; err != nil {
+		return err
+	}
+	if len(arr) == 0 || arr[0] != dim {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE note_vector DROP COLUMN IF EXISTS embedding").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(fmt.Sprintf("ALTER TABLE note_vector ADD COLUMN embedding vector(%d)", dim)).Error; err != nil {
+				return err
+			}
+			return tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&noteVector{}).Error
+		}); err != nil {
+			return err
+		}
+	}
+	return db.Exec("CREATE INDEX IF NOT EXISTS note_vector_embedding_idx " +
+		"ON note_vector USING hnsw (embedding vector_cosine_ops)").Error
+}
+
+func (srv *vectorSrv) Put(id uint64, v []float32) error {
+	if dim := model.Conf.Embedding.Dimension; dim > 0 && len(v) != dim {
+		return ErrDimension
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "note_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"vector"}),
+	}).Create(&noteVector{NoteID: id, Vector: embedding.Encode(v)}).Error; err != nil {
+		return err
+	}
+	if !model.Conf.IsPGVector() {
+		return nil
+	}
+	return db.Exec("UPDATE note_vector SET embedding = CAST(? AS vector) WHERE note_id = ?", pgVector(v), id).Error
+}
+
+func (srv *vectorSrv) Get(id uint64) []float32 {
+	var v noteVector
+	if err := db.Model(&noteVector{}).Where("note_id = ?", id).First(&v).Error; err != nil {
+		return nil
+	}
+	return embedding.Decode(v.Vector)
+}
+
+// Missing 还没有向量的笔记
+func (srv *vectorSrv) Missing(limit int) []*model.Note {
+	var arr []*model.Note
+	if err := db.Model(&model.Note{}).
+		Where("id NOT IN (?)", db.Model(&noteVector{}).Select("note_id")).
+		Order("id").Limit(limit).Find(&arr).Error; err != nil {
+		return nil
+	}
+	return arr
+}
+
+// scored 向量相似度
+type scored struct {
+	ID    uint64
+	Score float64
+}
+
+// Nearest 与 v 最相似的笔记，不含 exclude
+func (srv *vectorSrv) Nearest(v []float32, exclude uint64, limit int) []scored {
+	var arr []scored
+	if model.Conf.IsPGVector() {
+		s := pgVector(v)
+		db.Model(&noteVector{}).
+			Select("note_id AS id, 1 - (embedding <=> CAST(? AS vector)) AS score", s).
+			Where("note_id <> ? AND embedding IS NOT NULL", exclude).
+			Order(clause.Expr{SQL: "embedding <=> CAST(? AS vector)", Vars: []interface{}{s}}).
+			Limit(limit).
+			Scan(&arr)
+		return arr
+	}
+
+	// 笔记数量不多，全部取出在程序中计算
+	var batch []noteVector
+	db.Model(&noteVector{}).Where("note_id <> ?", exclude).
+		FindInBatches(&batch, 500, func(*gorm.DB, int) error {
+			for _, n := range batch {
+				arr = append(arr, scored{ID: n.NoteID, Score: embedding.Cosine(v, embedding.Decode(n.Vector))})
+			}
+			return nil
+		})
+	sort.Slice(arr, func(i, j int) bool {
+		if arr[i].Score != arr[j].Score {
+			return arr[i].Score > arr[j].Score
+		}
+		return arr[i].ID > arr[j].ID
+	})
+	if len(arr) > limit {
+		arr = arr[:limit]
+	}
+	return arr
+}
+
+// Similar 与笔记最相似的笔记及相似度
+func (srv *vectorSrv) Similar(id uint64, v []float32, limit int) ([]*model.Note, []float64) {
+	near := srv.Nearest(v, id, limit)
+	hits := make([]hit, 0, len(near))
+	score := make(map[uint64]float64, len(near))
+	for _, h := range near {
+		hits = append(hits, hit{ID: h.ID})
+		score[h.ID] = h.Score
+	}
+
+	notes := hitNotes(db, hits)
+	scores := make([]float64, 0, len(notes))
+	for _, n := range notes {
+		scores = append(scores, score[n.ID])
+	}
+	return notes, scores
+}
+
+func pgVector(v []float32) string {
+	arr := make([]string, 0, len(v))
+	for _, f := range v {
+		arr = append(arr, strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	return "[" + strings.Join(arr, ",") + "]"
+}
+
+const (
+	semanticDepth = 50 // 参与合并的全文搜索及向量结果的数量
+	rrfK          = 60 // Reciprocal Rank Fusion 的常数
+)
+
+// Semantic 使用 Reciprocal Rank Fusion 合并全文搜索与向量相似度的排序
+func Semantic(q *query.Query, v []float32, offset, limit int) (arr []*model.Note, count int64) {
+	var (
+		notes, _ = Search.Search(q, 0, semanticDepth)
+		score    = make(map[uint64]float64)
+		found    = make(map[uint64]hit)
+	)
+	for i, n := range notes {
+		score[n.ID] += 1 / float64(rrfK+i+1)
+		found[n.ID] = hit{ID: n.ID, Attachment: n.Attachment, Snippet: n.Snippet}
+	}
+
+	// 向量的结果不要求包含搜索词，但同样按标签、日期及排除的词过滤
+	near := Vector.Nearest(v, 0, semanticDepth)
+	if (q.HasFilter() || len(q.Not) > 0) && len(near) > 0 {
+		ids := make([]uint64, 0, len(near))
+		for _, h := range near {
+			ids = append(ids, h.ID)
+		}
+		var allowed []*model.Note
+		filter(db.Model(&model.Note{}).Where("note.id IN ?", ids), q).Find(&allowed)
+		ok := make(map[uint64]bool, len(allowed))
+		for _, n := range allowed {
+			ok[n.ID] = !excluded(q, n)
+		}
+		kept := near[:0]
+		for _, h := range near {
+			if ok[h.ID] {
+				kept = append(kept, h)
+			}
+		}
+		near = kept
+	}
+	for i, h := range near {
+		score[h.ID] += 1 / float64(rrfK+i+1)
+		if _, ok := found[h.ID]; !ok {
+			found[h.ID] = hit{ID: h.ID}
+		}
+	}
+
+	hits := make([]hit, 0, len(found))
+	for _, h := range found {
+		hits = append(hits, h)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if score[hits[i].ID] != score[hits[j].ID] {
+			return score[hits[i].ID] > score[hits[j].ID]
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	return hitNotes(db, pageHits(hits, offset, limit)), int64(len(hits))
+}
+
+// excluded 笔记包含排除的词，与全文搜索相同按分词比较，短语需要在原文中相邻。不检查附件中的文字
+func excluded(q *query.Query, n *model.Note) bool {
+	if len(q.Not) == 0 {
+		return false
+	}
+	title, all := tokenSet(n.Title), tokenSet(n.Title+"\n"+n.Content)
+	for _, t := range q.Not {
+		if len(t.Tokens) == 0 {
+			continue
+		}
+		if t.Phrase {
+			if contains(t, n) {
+				return true
+			}
+			continue
+		}
+		set := all
+		if t.Field == query.FieldTitle {
+			set = title
+		}
+		found := true
+		for _, v := range lowerTokens(t.Tokens) {
+			if !set[v] {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenSet(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, v := range participle.Tokens(s) {
+		m[strings.ToLower(v)] = true
+	}
+	return m
+}
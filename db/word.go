@@ -0,0 +1,81 @@
+package db
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/participle"
+)
+
+// noteWord 笔记中出现的词，英文不提取词干，用于补全还没有输入完的词，与全文搜索的实现无关
+type noteWord struct {
+	Word   string `gorm:"primaryKey"`
+	NoteID uint64 `gorm:"primaryKey;index"`
+}
+
+func (noteWord) TableName() string { return "note_word" }
+
+// initWords 第一次创建词表时，从已有的笔记中填充
+func initWords() error {
+	exists := db.Migrator().HasTable(&noteWord{})
+	if err := db.AutoMigrate(&noteWord{}); err != nil || exists {
+		return err
+	}
+
+	var arr []*model.Note
+	return db.Transaction(func(tx *gorm.DB) error {
+		return tx.Model(&model.Note{}).FindInBatches(&arr, rebuildBatch, func(*gorm.DB, int) error {
+			for _, v := range arr {
+				if err := saveWords(tx, v, attachmentText(tx, v.Content)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// saveWords 替换笔记的词表
+func saveWords(tx *gorm.DB, note *model.Note, attachment string) error {
+	if err := tx.Where("note_id = ?", note.ID).Delete(&noteWord{}).Error; err != nil {
+		return err
+	}
+
+	var (
+		arr  []noteWord
+		seen = make(map[string]bool)
+	)
+	for _, s := range []string{note.Title, note.Content, attachment} {
+		for _, w := range participle.Unstemmed(s) {
+			if !seen[w] {
+				seen[w] = true
+				arr = append(arr, noteWord{Word: w, NoteID: note.ID})
+			}
+		}
+	}
+	if len(arr) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(arr, 500).Error
+}
+
+// Complete 以 prefix 开头的词，按包含该词的笔记数量排序。范围条件用于使用主键的索引
+func Complete(prefix string, limit int) (arr []string) {
+	prefix = strings.ToLower(prefix)
+	if prefix == "" {
+		return nil
+	}
+	rs := []rune(prefix)
+	rs[len(rs)-1]++
+
+	db.Model(&noteWord{}).
+		Where("word >= ? AND word < ?", prefix, string(rs)).
+		Where(`word LIKE ? ESCAPE '\'`, escapeLike(prefix)+"%").
+		Group("word").
+		Order("COUNT(*) DESC, word").
+		Limit(limit).
+		Pluck("word", &arr)
+	return
+}
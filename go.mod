@@ -9,6 +9,7 @@ require (
 	go.uber.org/zap v1.18.1
 	go.x2ox.com/blackdatura v1.7.0
 	go.x2ox.com/tea v1.0.0
+	gopkg.in/yaml.v2 v2.4.0
 	gorm.io/driver/postgres v1.1.0
 	gorm.io/driver/sqlite v1.1.4
 	gorm.io/gorm v1.21.11
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // host=127.0.0.1 user=test password=123456789 dbname=dlink port=5432 sslmode=disable TimeZone=Asia/Shanghai
@@ -20,16 +21,66 @@ type Configuration struct {
 	TelegramID      int64  `json:"telegram_id"`      // 用户的 Telegram ID
 	TelegramToken   string `json:"telegram_token"`   // telegram bot token
 	TelegramWebhook string `json:"telegram_webhook"` // 默认地址 /api/v1/telegram/bot/webhook
+	Search          string `json:"search"`           // 全文搜索的实现，为空使用数据库自带的，inverted 为内置的倒排索引，index 为数据目录中的索引
+	Timezone        string `json:"timezone"`         // 提醒及摘要等定时任务使用的时区，如 Asia/Shanghai，默认为系统时区
 
 	Token struct {
 		AutoUpdate uint32 `json:"auto_update"` // 自动更新 key 的时间，单位 分钟。为零不自动更新
 		Preview    uint32 `json:"preview"`     // 预览的有效时间。为零不过期
 		View       uint32 `json:"view"`        // 阅读的有效时间
 		Share      uint32 `json:"share"`       // 分享的有效时间
+		Graph      uint32 `json:"graph"`       // 关系图的有效时间，默认 60，不能不过期
 	} `json:"token"`
+
+	Transcription struct {
+		Endpoint string `json:"endpoint"` // 语音识别服务地址，例如 whisper.cpp server 的 /inference。为空不识别
+		Language string `json:"language"` // 识别语言，默认 auto
+	} `json:"transcription"`
+
+	OCR struct {
+		Tesseract string `json:"tesseract"` // tesseract 可执行文件路径，优先使用
+		Endpoint  string `json:"endpoint"`  // OCR 服务地址，两者都为空不识别
+		Language  string `json:"language"`  // 识别语言，tesseract 默认 chi_sim+eng
+	} `json:"ocr"`
+
+	Document struct {
+		PDFToText string `json:"pdftotext"` // pdftotext 可执行文件路径，为空不提取 PDF 中的文字
+	} `json:"document"`
+
+	Title struct {
+		Length int `json:"length"` // 第一行作为标题的最大长度，默认 32
+	} `json:"title"`
+
+	Embedding struct {
+		Endpoint  string `json:"endpoint"`  // OpenAI 风格的 /v1/embeddings 接口地址，为空不启用语义搜索
+		Model     string `json:"model"`     // 模型名称
+		Key       string `json:"key"`       // 接口密钥，本地服务可以为空
+		PGVector  bool   `json:"pgvector"`  // PostgreSQL 使用 pgvector 计算相似度，否则在程序中计算
+		Dimension int    `json:"dimension"` // 向量的维度，使用 pgvector 时必填，修改后重新计算全部的向量
+	} `json:"embedding"`
+
+	Review struct {
+		Digest string `json:"digest"` // 每天发送待复习笔记的时间，如 09:00，为空不发送
+	} `json:"review"`
+
+	Digest struct {
+		Time   string `json:"time"`   // 发送摘要的时间，如 21:00，为空不发送
+		Period string `json:"period"` // day 每天发送当天的摘要，week 每周日发送一周的摘要，默认 day
+	} `json:"digest"`
 }
 
-var Conf Configuration
+var (
+	Conf     Configuration
+	location = time.Local
+)
+
+const (
+	SearchInverted = "inverted"
+	SearchIndex    = "index"
+
+	DigestDay  = "day"
+	DigestWeek = "week"
+)
 
 func (c Configuration) DSN() string {
 	if c.IsPostgreSQL() {
@@ -41,10 +92,29 @@ func (c Configuration) DSN() string {
 func (c Configuration) IsSQLite() bool          { return !strings.Contains(c.Database, "host=") }
 func (c Configuration) IsPostgreSQL() bool      { return strings.Contains(c.Database, "host=") }
 func (c Configuration) IsWebhook() bool         { return c.TelegramWebhook != "" }
+func (c Configuration) IsInverted() bool        { return c.Search == SearchInverted }
+func (c Configuration) IsIndex() bool           { return c.Search == SearchIndex }
+func (c Configuration) IsTranscription() bool   { return c.Transcription.Endpoint != "" }
+func (c Configuration) IsOCR() bool             { return c.OCR.Tesseract != "" || c.OCR.Endpoint != "" }
+func (c Configuration) IsEmbedding() bool       { return c.Embedding.Endpoint != "" }
+func (c Configuration) IsPGVector() bool        { return c.IsPostgreSQL() && c.Embedding.PGVector }
+func (c Configuration) IsWeeklyDigest() bool    { return c.Digest.Period == DigestWeek }
 func (c Configuration) Webhook() string         { return c.Domain + c.TelegramWebhook }
 func (c Configuration) TemplatesFolder() string { return filepath.Join(c.DataFolder, "/templates") }
 func (c Configuration) StaticFolder() string    { return filepath.Join(c.DataFolder, "/file") }
 func (c Configuration) LogFolder() string       { return filepath.Join(c.DataFolder, "/log/log") }
+func (c Configuration) IndexFile() string       { return filepath.Join(c.DataFolder, "/index/search.gob") }
+
+// Location 定时任务使用的时区
+func (c Configuration) Location() *time.Location { return location }
+
+// TitleLength 第一行作为标题的最大长度
+func (c Configuration) TitleLength() int {
+	if c.Title.Length > 0 {
+		return c.Title.Length
+	}
+	return 32
+}
 
 func mkdir(arr ...string) {
 	for _, v := range arr {
@@ -52,10 +122,11 @@ func mkdir(arr ...string) {
 	}
 }
 
-func LoadConfig() {
+// LoadConfig path 为空或读取失败时，使用默认的 /data/memo/config.json
+func LoadConfig(path string) {
 	var bts []byte
-	if len(os.Args) > 1 {
-		bts = readFile(os.Args[1])
+	if path != "" {
+		bts = readFile(path)
 	}
 	if bts == nil {
 		bts = readFile("/data/memo/config.json")
@@ -91,6 +162,22 @@ func (c *Configuration) check() error {
 	if c.LogLevel == "" {
 		c.LogLevel = "error"
 	}
+	if c.Token.Graph == 0 {
+		c.Token.Graph = 60
+	}
+	if c.Timezone != "" {
+		loc, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return err
+		}
+		location = loc
+	}
+	if c.IsPGVector() && c.Embedding.Dimension <= 0 {
+		return errors.New("config error: embedding.dimension is required with pgvector")
+	}
+	if c.Digest.Period != "" && c.Digest.Period != DigestDay && c.Digest.Period != DigestWeek {
+		return errors.New("config error: digest.period must be day or week")
+	}
 	return nil
 }
 
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type File struct {
+	ID        uint64         `gorm:"primaryKey" json:"id" `
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Name     string `gorm:"uniqueIndex" json:"name"` // 保存在 StaticFolder 中的文件名
+	Original string `json:"original"`                // 原始文件名，发送者未提供时为空
+	MimeType string `json:"mime_type"`               // 发送者声明的 MIME
+	Text     string `json:"text"`                    // 从附件中提取的文字，用于全文搜索
+}
+
+func (f *File) Path() string { return filepath.Join(Conf.StaticFolder(), f.Name) }
+
+// DisplayName 下载时使用的文件名，没有原始文件名时使用保存的文件名
+func (f *File) DisplayName() string {
+	if f.Original != "" {
+		return f.Original
+	}
+	return f.Name
+}
+
+var attachmentRegexp = regexp.MustCompile(`\]\(/file/([^)\s]+)\)`)
+
+// Attachments 内容中引用的附件文件名
+func Attachments(content string) []string {
+	var arr []string
+	for _, v := range attachmentRegexp.FindAllStringSubmatch(content, -1) {
+		arr = append(arr, v[1])
+	}
+	return arr
+}
+
+// HasAttachment 内容中是否引用了该附件
+func HasAttachment(content, name string) bool {
+	for _, v := range Attachments(content) {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
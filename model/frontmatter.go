@@ -0,0 +1,163 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FrontMatter 开头 --- 之间 YAML 格式的元数据，保存在 Note.Meta 中
+type FrontMatter struct {
+	Title    string            `json:"title,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Notebook string            `json:"notebook,omitempty"`
+	Aliases  []string          `json:"aliases,omitempty"`
+	Custom   map[string]string `json:"custom,omitempty"` // 其余的键
+}
+
+// ParseFrontMatter 解析开头的 front matter，返回其余的内容，没有或格式不对时返回 nil
+func ParseFrontMatter(text string) (*FrontMatter, string) {
+	block, rest, ok := splitFrontMatter(text)
+	if !ok {
+		return nil, text
+	}
+	m := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(block), &m); err != nil {
+		return nil, text
+	}
+
+	fm := &FrontMatter{}
+	for k, v := range m {
+		switch strings.ToLower(k) {
+		case "title":
+			fm.Title = strings.TrimSpace(yamlString(v))
+		case "tags", "tag":
+			fm.Tags = append(fm.Tags, yamlList(v)...)
+		case "notebook":
+			fm.Notebook = strings.TrimSpace(yamlString(v))
+		case "aliases", "alias":
+			fm.Aliases = append(fm.Aliases, yamlList(v)...)
+		default:
+			if fm.Custom == nil {
+				fm.Custom = make(map[string]string)
+			}
+			fm.Custom[k] = yamlString(v)
+		}
+	}
+	for i, v := range fm.Tags {
+		fm.Tags[i] = strings.TrimPrefix(v, "#")
+	}
+	return fm, rest
+}
+
+// yamlString 标量直接转为字符串，列表及映射使用 YAML 的流式写法
+func yamlString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}, map[interface{}]interface{}:
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(b))
+	}
+	return fmt.Sprint(v)
+}
+
+// yamlList 列表或逗号分隔的字符串
+func yamlList(v interface{}) []string {
+	var arr []string
+	switch v := v.(type) {
+	case []interface{}:
+		for _, s := range v {
+			if s := strings.TrimSpace(yamlString(s)); s != "" {
+				arr = append(arr, s)
+			}
+		}
+	default:
+		for _, s := range strings.Split(yamlString(v), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				arr = append(arr, s)
+			}
+		}
+	}
+	return arr
+}
+
+// ParseMeta 从内容中重新解析 front matter，以及未完成的任务数量
+func (n *Note) ParseMeta() {
+	n.Notebook, n.Meta = "", ""
+	if fm, _ := ParseFrontMatter(n.Content); fm != nil {
+		n.Notebook = fm.Notebook
+		if b, err := json.Marshal(fm); err == nil {
+			n.Meta = string(b)
+		}
+	}
+	n.Todo = n.openTasks()
+}
+
+// FrontMatter 保存的元数据，没有时返回 nil
+func (n *Note) FrontMatter() *FrontMatter {
+	if n.Meta == "" {
+		return nil
+	}
+	fm := &FrontMatter{}
+	if err := json.Unmarshal([]byte(n.Meta), fm); err != nil {
+		return nil
+	}
+	return fm
+}
+
+// body 去掉 front matter 后的内容
+func (n *Note) body() string {
+	if n.Meta == "" {
+		return n.Content
+	}
+	_, rest := ParseFrontMatter(n.Content)
+	return rest
+}
+
+// metaHTML 元数据表格，中间不能有空行
+func (n *Note) metaHTML() string {
+	fm := n.FrontMatter()
+	if fm == nil {
+		return ""
+	}
+	var rows [][2]string
+	if fm.Notebook != "" {
+		rows = append(rows, [2]string{"Notebook", fm.Notebook})
+	}
+	if len(fm.Tags) != 0 {
+		rows = append(rows, [2]string{"Tags", "#" + strings.Join(fm.Tags, " #")})
+	}
+	if len(fm.Aliases) != 0 {
+		rows = append(rows, [2]string{"Aliases", strings.Join(fm.Aliases, ", ")})
+	}
+	keys := make([]string, 0, len(fm.Custom))
+	for k := range fm.Custom {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		rows = append(rows, [2]string{k, fm.Custom[k]})
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<table class="front-matter">`)
+	for _, v := range rows {
+		sb.WriteString("<tr><th>" + html.EscapeString(v[0]) + "</th><td>" +
+			strings.ReplaceAll(html.EscapeString(v[1]), "\n", "<br />") + "</td></tr>")
+	}
+	sb.WriteString("</table>\n\n")
+	return sb.String()
+}
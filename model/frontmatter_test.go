@@ -0,0 +1,96 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		in          string
+		block, rest string
+		ok          bool
+	}{
+		{"---\ntitle: a\n---\nbody", "title: a\n", "body", true},
+		{"---\r\ntitle: a\r\n---\r\nbody", "title: a\r\n", "body", true},
+		{"---\ntitle: a\n---", "title: a\n", "", true},
+		{"---\ntitle: a\n---  \nbody", "title: a\n", "body", true},
+		{"---\n---\nbody", "", "body", true},
+		{"---\ntitle: a\nbody", "", "---\ntitle: a\nbody", false},
+		{"---", "", "---", false},
+		{"body\n---\ntitle: a\n---\n", "", "body\n---\ntitle: a\n---\n", false},
+		{"--- \ntitle: a\n---\n", "", "--- \ntitle: a\n---\n", false},
+		{"---\na: |\n  ----\n---\nbody", "a: |\n  ----\n", "body", true},
+	}
+	for _, tt := range tests {
+		block, rest, ok := splitFrontMatter(tt.in)
+		if block != tt.block || rest != tt.rest || ok != tt.ok {
+			t.Errorf("splitFrontMatter(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.in, block, rest, ok, tt.block, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *FrontMatter
+		rest string
+	}{
+		{"---\ntitle: Hello\ntags: [go, \"#memo\"]\nnotebook: work\n---\nbody",
+			&FrontMatter{Title: "Hello", Tags: []string{"go", "memo"}, Notebook: "work"}, "body"},
+		{"---\nTags: go, memo\nalias: [a, b]\n---\n",
+			&FrontMatter{Tags: []string{"go", "memo"}, Aliases: []string{"a", "b"}}, ""},
+		{"---\ntags:\n  - go\n  - 2021\n---\n", &FrontMatter{Tags: []string{"go", "2021"}}, ""},
+		{"---\nstatus: draft\npriority: 1\nlist: [a, b]\nempty:\n---\n",
+			&FrontMatter{Custom: map[string]string{"status": "draft", "priority": "1", "list": "- a\n- b", "empty": ""}}, ""},
+		{"---\n---\nbody", &FrontMatter{}, "body"},
+		{"---\ntitle: [a\n---\nbody", nil, "---\ntitle: [a\n---\nbody"},
+		{"---\n- a\n---\nbody", nil, "---\n- a\n---\nbody"},
+		{"no front matter", nil, "no front matter"},
+	}
+	for _, tt := range tests {
+		got, rest := ParseFrontMatter(tt.in)
+		if !reflect.DeepEqual(got, tt.want) || rest != tt.rest {
+			t.Errorf("ParseFrontMatter(%q) = %+v, %q, want %+v, %q", tt.in, got, rest, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestNoteBody(t *testing.T) {
+	tests := []struct {
+		content string
+		body    string
+	}{
+		{"---\ntitle: a\n---\nbody #tag", "body #tag"},
+		{"---\nbroken: [\n---\nbody", "---\nbroken: [\n---\nbody"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		n := &Note{Content: tt.content}
+		n.ParseMeta()
+		if got := n.body(); got != tt.body {
+			t.Errorf("body(%q) = %q, want %q", tt.content, got, tt.body)
+		}
+	}
+}
+
+func TestNewNoteFrontMatter(t *testing.T) {
+	tests := []struct {
+		text           string
+		title, content string
+		notebook       string
+	}{
+		{"---\ntitle: Meta\nnotebook: work\n---\n# Heading\nbody", "Meta", "---\ntitle: Meta\nnotebook: work\n---\n# Heading\nbody", "work"},
+		{"---\ntags: [a]\n---\n# Heading #\n\nbody", "Heading", "---\ntags: [a]\n---\nbody", ""},
+		{"---\ntags: [a]\n---\nShort line\nbody", "Short line", "---\ntags: [a]\n---\nbody", ""},
+		{"---\ntitle: \"\"\n---\nfirst. second", "first", "---\ntitle: \"\"\n---\nfirst. second", ""},
+	}
+	for _, tt := range tests {
+		n := NewNote("", tt.text)
+		if n.Title != tt.title || n.Content != tt.content || n.Notebook != tt.notebook {
+			t.Errorf("NewNote(%q) = %q, %q, %q, want %q, %q, %q",
+				tt.text, n.Title, n.Content, n.Notebook, tt.title, tt.content, tt.notebook)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// History 搜索模式中的搜索记录，相同的内容只保留一条
+type History struct {
+	ID        uint64    `gorm:"primaryKey" json:"id" `
+	UpdatedAt time.Time `gorm:"index" json:"updated_at"`
+
+	Query string `gorm:"uniqueIndex" json:"query"`
+}
@@ -12,6 +12,7 @@ type Input struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	MessageID int
+	MessageID int    // 消息 ID，模板展开的内容为负数
 	Content   string `json:"content"` // 内容
+	Title     bool   `json:"title"`   // 为 true 时内容为 /title 设置的标题
 }
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// JournalLayout 日记标题中的日期
+const JournalLayout = "2006-01-02"
+
+// JournalTitle 每天一篇的日记，如 2026-10-18 Journal
+func JournalTitle(day time.Time) string { return day.Format(JournalLayout) + " Journal" }
+
+// JournalEntry 一条日记，以时间开头
+func JournalEntry(now time.Time, text string) string {
+	return "**" + now.Format("15:04") + "** " + strings.TrimRight(text, "\n")
+}
+
+var journalEntryRegexp = regexp.MustCompile(`(?m)^\*\*\d{2}:\d{2}\*\* `)
+
+// JournalEntries 日记中的记录数量
+func JournalEntries(content string) int {
+	return len(journalEntryRegexp.FindAllStringIndex(content, -1))
+}
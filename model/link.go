@@ -0,0 +1,176 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// NoteLink 笔记内容中的 [[标题]] 或 [[#ID]]，渲染时再解析到笔记，修改标题后不需要更新
+type NoteLink struct {
+	FromID uint64 `gorm:"primaryKey"`
+	Target string `gorm:"primaryKey;index"` // 标题，或者 #ID
+}
+
+// GraphEdge 关系图中的引用
+type GraphEdge struct {
+	FromID uint64 `json:"from_id"`
+	ToID   uint64 `json:"to_id"`
+}
+
+// GraphNode 关系图中的笔记，Backlinks 为被引用的次数
+type GraphNode struct {
+	ID        uint64 `json:"id"`
+	Title     string `json:"title"`
+	Backlinks int    `json:"backlinks"`
+	URL       string `json:"url"`
+}
+
+// LinkGraph 有引用关系的笔记及引用
+type LinkGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Links []GraphEdge `json:"links"`
+}
+
+func GraphLink() string {
+	return fmt.Sprintf("%s/graph/%s", Conf.Domain, NewToken(Graph, 0))
+}
+
+// GraphNoteLink 关系图中笔记的链接，打开时才生成阅读的链接，关系图过期后不能再打开
+func GraphNoteLink(token string, id uint64) string {
+	return fmt.Sprintf("%s/graph/%s/note/%d", Conf.Domain, token, id)
+}
+
+var wikiLinkRegexp = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// wikiLinkIndexes 正文中 [[...]] 的位置，与预览页面使用相同的 Markdown 解析，只查找文字，代码及链接中的不是引用
+func wikiLinkIndexes(body string) [][]int {
+	var (
+		arr         [][]int
+		start, stop = -1, -1 // 相邻的文字合并后再查找，未组成链接的 [ 及 ] 是单独的文字节点
+	)
+	flush := func() {
+		if start >= 0 {
+			for _, m := range wikiLinkRegexp.FindAllStringIndex(body[start:stop], -1) {
+				arr = append(arr, []int{start + m[0], start + m[1]})
+			}
+		}
+		start, stop = -1, -1
+	}
+
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader([]byte(body)))
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := node.(type) {
+		case *ast.CodeSpan, *ast.Link, *ast.Image, *ast.AutoLink, *ast.RawHTML:
+			flush()
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if node.Segment.Start != stop {
+				flush()
+				start = node.Segment.Start
+			}
+			stop = node.Segment.Stop
+		}
+		return ast.WalkContinue, nil
+	})
+	flush()
+	return arr
+}
+
+// WikiLinks 正文中引用的目标，去掉了首尾的空白
+func (n *Note) WikiLinks() []string {
+	var (
+		body = n.body()
+		arr  []string
+		seen = make(map[string]bool)
+	)
+	for _, m := range wikiLinkIndexes(body) {
+		target := strings.TrimSpace(body[m[0]+2 : m[1]-2])
+		if target != "" && !seen[target] {
+			seen[target] = true
+			arr = append(arr, target)
+		}
+	}
+	return arr
+}
+
+// WikiLinkID [[#123]] 形式的引用返回笔记 ID
+func WikiLinkID(target string) (uint64, bool) {
+	if !strings.HasPrefix(target, "#") {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(target[1:], 10, 64)
+	return id, err == nil
+}
+
+// WikiLinkTarget 保存在 NoteLink 中的目标，[[#0123]] 与 [[#123]] 相同
+func WikiLinkTarget(target string) string {
+	if id, ok := WikiLinkID(target); ok {
+		return "#" + strconv.FormatUint(id, 10)
+	}
+	return target
+}
+
+var markdownLinkReplacer = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`)
+
+// renderWikiLinks 解析到的引用替换为链接，其余的只保留文字。origin 将替换后的位置转换为 content 中的位置
+func (n *Note) renderWikiLinks(content string) (s string, origin func(int) int) {
+	var (
+		sb    strings.Builder
+		last  int
+		spans [][2]int // 每次替换后的结尾，及 content 中对应的结尾
+	)
+	for _, m := range wikiLinkIndexes(content) {
+		sb.WriteString(content[last:m[0]])
+		sb.WriteString(n.wikiLink(content[m[0]:m[1]]))
+		spans = append(spans, [2]int{sb.Len(), m[1]})
+		last = m[1]
+	}
+	sb.WriteString(content[last:])
+
+	return sb.String(), func(i int) int {
+		var shift int
+		for _, v := range spans {
+			if v[0] > i {
+				break
+			}
+			shift = v[1] - v[0]
+		}
+		return i + shift
+	}
+}
+
+func (n *Note) wikiLink(s string) string {
+	target := strings.TrimSpace(s[2 : len(s)-2])
+	text := markdownLinkReplacer.Replace(target)
+	to, ok := n.Links[target]
+	if !ok || to == nil {
+		return text
+	}
+	if _, isID := WikiLinkID(target); isID {
+		text = markdownLinkReplacer.Replace(to.Title)
+	}
+	return "[" + text + "](" + to.ViewLink() + ")"
+}
+
+// backlinksMarkdown 引用了该笔记的笔记
+func (n *Note) backlinksMarkdown() string {
+	if len(n.Backlinks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\n**Linked from**\n\n")
+	for _, v := range n.Backlinks {
+		sb.WriteString("- [" + markdownLinkReplacer.Replace(v.Title) + "](" + v.ViewLink() + ")\n")
+	}
+	return sb.String()
+}
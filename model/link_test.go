@@ -0,0 +1,96 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWikiLinks(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"see [[Go memo]] and [[#12]]", []string{"Go memo", "#12"}},
+		{"[[ a ]] [[a]] [[b]]", []string{"a", "b"}},
+		{"[[]] [[  ]] [[a\nb]] [[[a]]]", []string{"a"}},
+		{"no links [a](b)", nil},
+		{"**[[a]]** _[[b]]_", []string{"a", "b"}},
+		{"`[[code]]` and [[a]]", []string{"a"}},
+		{"```\n[[fenced]]\n```\n\n    [[indented]]\n\n[[a]]", []string{"a"}},
+		{"[see [[a]]](https://x.com) <b>[[b]]</b>", []string{"b"}},
+		{"- [ ] [[a]]\n\n| [[b]] |\n| --- |", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := (&Note{Content: tt.in}).WikiLinks(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WikiLinks(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWikiLinkTarget(t *testing.T) {
+	tests := []struct {
+		in, want string
+		id       uint64
+		ok       bool
+	}{
+		{"#12", "#12", 12, true},
+		{"#0012", "#12", 12, true},
+		{"#tag", "#tag", 0, false},
+		{"#", "#", 0, false},
+		{"12", "12", 0, false},
+		{"Title", "Title", 0, false},
+	}
+	for _, tt := range tests {
+		id, ok := WikiLinkID(tt.in)
+		if got := WikiLinkTarget(tt.in); got != tt.want || id != tt.id || ok != tt.ok {
+			t.Errorf("WikiLinkTarget(%q) = %q, %d, %v, want %q, %d, %v", tt.in, got, id, ok, tt.want, tt.id, tt.ok)
+		}
+	}
+}
+
+func TestRenderWikiLinks(t *testing.T) {
+	n := &Note{Links: map[string]*Note{
+		"Go":  {ID: 1, Title: "Go"},
+		"#2":  {ID: 2, Title: "a [b]"},
+		"Nil": nil,
+	}}
+	tests := []struct {
+		in             string
+		prefix, suffix string // 链接中的 token 带有时间，只比较前后的部分
+	}{
+		{"x [[Go]] y", "x [Go](/preview/", ") y"},
+		{"[[#2]]", `[a \[b\]](/preview/`, ")"},
+		{"[[Missing]] [[Nil]]", "Missing Nil", ""},
+		{"[[a]b]]", "[[a]b]]", ""},
+		{"`[[Go]]` [[Missing]]", "`[[Go]]` Missing", ""},
+		{"```\n[[Go]]\n```", "```\n[[Go]]\n```", ""},
+	}
+	for _, tt := range tests {
+		got, _ := n.renderWikiLinks(tt.in)
+		if !strings.HasPrefix(got, tt.prefix) || !strings.HasSuffix(got, tt.suffix) ||
+			(tt.suffix == "" && got != tt.prefix) {
+			t.Errorf("renderWikiLinks(%q) = %q, want %q…%q", tt.in, got, tt.prefix, tt.suffix)
+		}
+	}
+
+	// 替换后每个 | 的位置都能转换回原文中 | 的位置
+	for _, in := range []string{"| [[Missing]] | [[Go]] |", "[[#2]]|[[Other]]|", "`[[Go]]` | [[Go]] |", "|"} {
+		s, origin := n.renderWikiLinks(in)
+		var want []int
+		for i := range in {
+			if in[i] == '|' {
+				want = append(want, i)
+			}
+		}
+		var got []int
+		for i := range s {
+			if s[i] == '|' {
+				got = append(got, origin(i))
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("renderWikiLinks(%q) origin = %v, want %v", in, got, want)
+		}
+	}
+}
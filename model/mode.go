@@ -5,6 +5,9 @@ type Mode string
 const (
 	ModeInput  Mode = "输入模式"
 	ModeSearch Mode = "搜索模式"
+
+	ModeSemantic Mode = "语义搜索模式" // 合并全文搜索与向量相似度，需要配置 embedding
+	ModeJournal  Mode = "日记模式"   // 消息追加到当天的日记
 )
 
 func (m Mode) String() string { return string(m) }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +27,51 @@ type Note struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	Title     string         `json:"title"`   // 标题
 	Content   string         `json:"content"` // 内容
+
+	Pinned   bool       `gorm:"index" json:"pinned"`    // 置顶，内联查询为空时优先显示
+	ViewedAt *time.Time `gorm:"index" json:"viewed_at"` // 最近一次查看的时间
+
+	Notebook string `gorm:"index" json:"notebook"` // front matter 中的 notebook
+	Meta     string `json:"meta"`                  // front matter 的 JSON，见 FrontMatter
+	Journal  string `gorm:"index" json:"journal"`  // 日记的日期，如 2026-10-18，其他笔记为空
+	Todo     int    `gorm:"index" json:"todo"`     // 未完成的任务数量
+
+	Attachment bool   `gorm:"-" json:"-"` // 搜索结果，附件中的文字有匹配
+	Snippet    string `gorm:"-" json:"-"` // 搜索结果，匹配词附近的片段，匹配词由 HighlightStart 及 HighlightEnd 包围
+
+	Links     map[string]*Note `gorm:"-" json:"-"` // 渲染时 [[...]] 解析到的笔记，为空时只显示文字
+	Backlinks []*Note          `gorm:"-" json:"-"` // 渲染时显示的引用了该笔记的笔记
+	Editable  bool             `gorm:"-" json:"-"` // 渲染时允许勾选任务，分享的页面不允许
+}
+
+const (
+	HighlightStart = "\x01"
+	HighlightEnd   = "\x02"
+)
+
+var tagRegexp = regexp.MustCompile(`(?:^|\s)#([^\s#]+)`)
+
+// Tags front matter 中的标签及正文中 #标签 形式的标签
+func (n *Note) Tags() []string {
+	var (
+		arr  []string
+		seen = make(map[string]bool)
+	)
+	if fm := n.FrontMatter(); fm != nil {
+		for _, v := range fm.Tags {
+			if !seen[v] {
+				seen[v] = true
+				arr = append(arr, v)
+			}
+		}
+	}
+	for _, v := range tagRegexp.FindAllStringSubmatch(n.body(), -1) {
+		if !seen[v[1]] {
+			seen[v[1]] = true
+			arr = append(arr, v[1])
+		}
+	}
+	return arr
 }
 
 func (n *Note) ParticipleTitle() string   { return participle.Parse(n.Title) }
@@ -37,13 +83,44 @@ func (n *Note) PreviewLink() string { return n.link(Preview) }
 func (n *Note) ViewLink() string    { return n.link(View) }
 func (n *Note) ShareLink() string   { return n.link(Share) }
 func (n *Note) List() string {
-	return fmt.Sprintf(`%d \| %s%s%s \| %s
-`,
+	return fmt.Sprintf(`%d \| %s%s%s \| %s%s
+%s`,
 		n.ID,
 		"`", n.CreatedAt.Format("2006-01-02 15:04"), "`",
-		n.MarkdownLink(),
+		n.MarkdownLink(), n.attachmentMark(),
+		n.snippetMarkdownV2(),
 	)
 }
+
+// snippetMarkdownV2 匹配词加粗，没有片段时为空
+func (n *Note) snippetMarkdownV2() string {
+	if n.Snippet == "" {
+		return ""
+	}
+	s := util.EscapedMarkdownV2(strings.Join(strings.Fields(n.Snippet), " "))
+	s = strings.ReplaceAll(s, HighlightStart, "*")
+	s = strings.ReplaceAll(s, HighlightEnd, "*")
+	return s + "\n\n"
+}
+
+// snippetText 去掉高亮标记的片段
+func (n *Note) snippetText() string {
+	s := strings.ReplaceAll(n.Snippet, HighlightStart, "")
+	s = strings.ReplaceAll(s, HighlightEnd, "")
+	return strings.Join(strings.Fields(s), " ")
+}
+func (n *Note) attachmentMark() string {
+	if n.Attachment {
+		return " 📎"
+	}
+	return ""
+}
+func (n *Note) pinMark() string {
+	if n.Pinned {
+		return "📌 "
+	}
+	return ""
+}
 func (n *Note) MarkdownLink() string {
 	return fmt.Sprintf(`[%s](%s)`, util.EscapedMarkdownV2(n.Title), n.ViewLink())
 }
@@ -51,24 +128,36 @@ func (n *Note) HTML() template.HTML {
 	if n == nil {
 		return ""
 	}
-	var buf bytes.Buffer
-
-	if err := goldmark.New(
-		goldmark.WithExtensions(extension.GFM),
-		goldmark.WithParserOptions(
-			parser.WithAutoHeadingID(),
-		),
-		goldmark.WithRendererOptions(
-			html.WithHardWraps(),
-			html.WithXHTML(),
-			html.WithUnsafe(),
-		),
-	).Convert([]byte(
-		fmt.Sprintf("# %s\n\n %s \n\n <hr />%s", n.Title, n.Content, n.CreatedAt.Format("2006-01-02 15:04")),
+	var (
+		buf          bytes.Buffer
+		prefix       = fmt.Sprintf("# %s\n\n%s ", n.Title, n.metaHTML())
+		body, origin = n.renderWikiLinks(n.body())
+		options      = []goldmark.Option{
+			goldmark.WithExtensions(extension.GFM),
+			goldmark.WithParserOptions(
+				parser.WithAutoHeadingID(),
+			),
+			goldmark.WithRendererOptions(
+				html.WithHardWraps(),
+				html.WithXHTML(),
+				html.WithUnsafe(),
+			),
+		}
+	)
+	if n.Editable {
+		options = append(options, n.taskOptions(len(prefix), len(body), origin)...)
+	}
+
+	if err := goldmark.New(options...).Convert([]byte(
+		fmt.Sprintf("%s%s \n\n <hr />%s%s", prefix, body,
+			n.CreatedAt.Format("2006-01-02 15:04"), n.backlinksMarkdown()),
 	), &buf); err != nil {
 		return ""
 	}
 
+	if n.Editable && strings.Contains(buf.String(), "data-task=") {
+		buf.WriteString(taskScript)
+	}
 	return template.HTML(buf.String())
 }
 
@@ -79,11 +168,19 @@ func (n *Note) Description() string {
 	return string([]rune(n.Content)[:256]) + "..."
 }
 
+// searchDescription 有片段时使用片段作为描述
+func (n *Note) searchDescription() string {
+	if n.Snippet != "" {
+		return n.snippetText()
+	}
+	return n.Description()
+}
+
 func (n *Note) InlineQueryResultArticle() dandelion.InlineQueryResultArticle {
 	return dandelion.InlineQueryResultArticle{
 		Type:  "article",
 		ID:    strconv.FormatUint(n.ID, 10),
-		Title: n.Title,
+		Title: n.pinMark() + n.Title,
 		InputMessageContent: dandelion.InputTextMessageContent{
 			Text:                  n.Description(),
 			DisableWebPagePreview: true,
@@ -93,22 +190,7 @@ func (n *Note) InlineQueryResultArticle() dandelion.InlineQueryResultArticle {
 				{dandelion.NewInlineKeyboardButtonURL("查看内容", n.ShareLink())},
 			},
 		},
-		Description: n.Description(),
+		Description: strings.TrimSpace(n.attachmentMark() + " " + n.searchDescription()),
 		ThumbURL:    defaultImage,
 	}
 }
-
-// NewNote 标题规则
-// 如果设置了标题，默认为第一行作为标题，超过 32 个字符的，认定为无标题
-func NewNote(text string) *Note {
-	n := &Note{
-		Title:   "无标题文档",
-		Content: text,
-	}
-	str := strings.SplitN(text, "\n", 2)
-	if len(str) == 2 && len([]rune(str[0])) <= 32 {
-		n.Title = str[0]
-		n.Content = str[1]
-	}
-	return n
-}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/x2ox/memo/pkg/when"
+)
+
+// Reminder 笔记的提醒，到时间后由机器人发送笔记
+type Reminder struct {
+	ID        uint64    `gorm:"primaryKey" json:"id" `
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	NoteID uint64    `gorm:"index" json:"note_id"`
+	At     time.Time `gorm:"index" json:"at"`    // 下一次提醒的时间
+	Repeat when.Unit `json:"repeat"`             // 重复的单位
+	Every  int       `json:"every"`              // 每隔几个单位重复
+	Day    int       `json:"day"`                // 每月重复的日期，At 移到月底后仍回到这一天
+	Fired  bool      `gorm:"index" json:"fired"` // 不重复的提醒已经发送，等待推迟或完成
+}
+
+// Rule 提醒的规则，时间转换到配置的时区，重复时按当地的日期计算
+func (r *Reminder) Rule() when.Rule {
+	return when.Rule{At: r.At.In(Conf.Location()), Unit: r.Repeat, Every: r.Every, Day: r.Day}
+}
+
+// IsRepeat 是否为重复的提醒
+func (r *Reminder) IsRepeat() bool { return r.Repeat != when.Once }
+
+// Describe 提醒的时间及重复规则
+func (r *Reminder) Describe() string {
+	rule := r.Rule()
+	s := rule.At.Format("2006-01-02 15:04")
+	if repeat := rule.String(); repeat != "" {
+		s += " (" + repeat + ")"
+	}
+	return s
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Grade 复习时的评分
+type Grade uint8
+
+const (
+	GradeAgain Grade = iota // 忘记了，10 分钟后重来
+	GradeHard               // 想起来很吃力
+	GradeGood               // 想起来了
+	GradeEasy               // 很轻松
+)
+
+const (
+	reviewEase    = 2.5 // 初始的难度系数
+	reviewMinEase = 1.3
+	reviewAgain   = 10 * time.Minute
+)
+
+// Review 加入复习队列的笔记，按 SM-2 算法安排下一次复习
+type Review struct {
+	ID        uint64    `gorm:"primaryKey" json:"id" `
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	NoteID   uint64    `gorm:"uniqueIndex" json:"note_id"`
+	Due      time.Time `gorm:"index" json:"due"` // 下一次复习的时间
+	Interval int       `json:"interval"`         // 复习间隔，单位 天
+	Ease     float64   `json:"ease"`             // 难度系数，越大间隔增长越快
+	Reps     int       `json:"reps"`             // 连续记住的次数
+	Lapses   int       `json:"lapses"`           // 忘记的次数
+}
+
+// NewReview 新加入的笔记立即可以复习
+func NewReview(noteID uint64, now time.Time) *Review {
+	return &Review{NoteID: noteID, Due: now, Ease: reviewEase}
+}
+
+// Schedule 根据评分安排下一次复习
+func (r *Review) Schedule(g Grade, now time.Time) {
+	if r.Ease < reviewMinEase {
+		r.Ease = reviewEase
+	}
+	if g == GradeAgain {
+		r.Reps, r.Interval = 0, 0
+		r.Lapses++
+		r.Ease = math.Max(reviewMinEase, r.Ease-0.2)
+		r.Due = now.Add(reviewAgain)
+		return
+	}
+
+	var interval float64
+	switch {
+	case r.Reps == 0:
+		interval = 1
+		if g == GradeEasy {
+			interval = 4
+		}
+	case r.Reps == 1:
+		interval = 6
+	default:
+		interval = float64(r.Interval) * r.Ease
+	}
+	switch g {
+	case GradeHard:
+		r.Ease = math.Max(reviewMinEase, r.Ease-0.15)
+		if r.Reps != 0 {
+			interval = float64(r.Interval) * 1.2
+		}
+	case GradeEasy:
+		r.Ease += 0.15
+		if r.Reps != 0 {
+			interval *= 1.3
+		}
+	}
+
+	// 间隔至少比上一次多一天
+	r.Interval = int(math.Max(float64(r.Interval+1), math.Round(interval)))
+	r.Reps++
+	r.Due = now.AddDate(0, 0, r.Interval)
+}
+
+// Preview 评分后的复习间隔，显示在按钮上
+func (r Review) Preview(g Grade, now time.Time) string {
+	r.Schedule(g, now)
+	if r.Interval == 0 {
+		return "10m"
+	}
+	return FormatDays(r.Interval)
+}
+
+// FormatDays 以天、月或年显示间隔
+func FormatDays(days int) string {
+	switch {
+	case days < 30:
+		return strconv.Itoa(days) + "d"
+	case days < 365:
+		return strconv.Itoa(int(math.Round(float64(days)/30))) + "mo"
+	}
+	return strconv.Itoa(int(math.Round(float64(days)/365))) + "y"
+}
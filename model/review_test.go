@@ -0,0 +1,81 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReviewSchedule(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		r     Review
+		g     Grade
+		want  Review
+		after time.Duration
+	}{
+		{Review{Ease: 2.5}, GradeAgain, Review{Ease: 2.3, Lapses: 1}, reviewAgain},
+		{Review{Ease: 2.5}, GradeHard, Review{Ease: 2.35, Reps: 1, Interval: 1}, 24 * time.Hour},
+		{Review{Ease: 2.5}, GradeGood, Review{Ease: 2.5, Reps: 1, Interval: 1}, 24 * time.Hour},
+		{Review{Ease: 2.5}, GradeEasy, Review{Ease: 2.65, Reps: 1, Interval: 4}, 4 * 24 * time.Hour},
+		{Review{Ease: 2.5, Reps: 1, Interval: 1}, GradeHard, Review{Ease: 2.35, Reps: 2, Interval: 2}, 2 * 24 * time.Hour},
+		{Review{Ease: 2.5, Reps: 1, Interval: 1}, GradeGood, Review{Ease: 2.5, Reps: 2, Interval: 6}, 6 * 24 * time.Hour},
+		{Review{Ease: 2.5, Reps: 1, Interval: 1}, GradeEasy, Review{Ease: 2.65, Reps: 2, Interval: 8}, 8 * 24 * time.Hour},
+		{Review{Ease: 2.5, Reps: 2, Interval: 6}, GradeHard, Review{Ease: 2.35, Reps: 3, Interval: 7}, 7 * 24 * time.Hour},
+		{Review{Ease: 2.5, Reps: 2, Interval: 6}, GradeGood, Review{Ease: 2.5, Reps: 3, Interval: 15}, 15 * 24 * time.Hour},
+		{Review{Ease: 2.5, Reps: 2, Interval: 6}, GradeEasy, Review{Ease: 2.65, Reps: 3, Interval: 20}, 20 * 24 * time.Hour},
+		{Review{Ease: 2.5, Reps: 5, Interval: 30, Lapses: 1}, GradeAgain, Review{Ease: 2.3, Lapses: 2}, reviewAgain},
+		{Review{Ease: 1.4}, GradeAgain, Review{Ease: 1.3, Lapses: 1}, reviewAgain},
+		{Review{Ease: 1.3, Reps: 2, Interval: 10}, GradeHard, Review{Ease: 1.3, Reps: 3, Interval: 12}, 12 * 24 * time.Hour},
+		{Review{Ease: 1.3, Reps: 2, Interval: 1}, GradeGood, Review{Ease: 1.3, Reps: 3, Interval: 2}, 2 * 24 * time.Hour},
+		{Review{Reps: 2, Interval: 6}, GradeGood, Review{Ease: 2.5, Reps: 3, Interval: 15}, 15 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		r := tt.r
+		r.Schedule(tt.g, now)
+		tt.want.Due = now.Add(tt.after)
+		if r.Reps != tt.want.Reps || r.Interval != tt.want.Interval || r.Lapses != tt.want.Lapses ||
+			!r.Due.Equal(tt.want.Due) || r.Ease < tt.want.Ease-1e-9 || r.Ease > tt.want.Ease+1e-9 {
+			t.Errorf("%+v.Schedule(%d) = %+v, want %+v", tt.r, tt.g, r, tt.want)
+		}
+	}
+}
+
+func TestReviewPreview(t *testing.T) {
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		r    Review
+		g    Grade
+		want string
+	}{
+		{Review{Ease: 2.5}, GradeAgain, "10m"},
+		{Review{Ease: 2.5}, GradeGood, "1d"},
+		{Review{Ease: 2.5}, GradeEasy, "4d"},
+		{Review{Ease: 2.5, Reps: 3, Interval: 20}, GradeGood, "2mo"},
+		{Review{Ease: 2.5, Reps: 4, Interval: 200}, GradeEasy, "2y"},
+	}
+	for _, tt := range tests {
+		r := tt.r
+		if got := r.Preview(tt.g, now); got != tt.want || r != tt.r {
+			t.Errorf("%+v.Preview(%d) = %q, want %q", tt.r, tt.g, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDays(t *testing.T) {
+	tests := []struct {
+		days int
+		want string
+	}{
+		{0, "0d"},
+		{29, "29d"},
+		{30, "1mo"},
+		{364, "12mo"},
+		{365, "1y"},
+		{800, "2y"},
+	}
+	for _, tt := range tests {
+		if got := FormatDays(tt.days); got != tt.want {
+			t.Errorf("FormatDays(%d) = %q, want %q", tt.days, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// ScheduleRun 定时任务最近一次执行的日期，重启后据此补发错过的一次
+type ScheduleRun struct {
+	Name      string    `gorm:"primaryKey" json:"name"` // 定时任务名称
+	UpdatedAt time.Time `json:"updated_at"`
+	Last      string    `json:"last"` // 最近一次执行的日期，如 2026-10-18
+}
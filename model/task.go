@@ -0,0 +1,159 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Task 笔记中的任务，即 - [ ] 或 - [x] 开头的列表项
+type Task struct {
+	Offset int    // [ 在 Content 中的位置，用于定位任务
+	Done   bool   // 已完成
+	Text   string // 任务内容
+}
+
+// eachCheckbox 依次处理文档中的复选框，offset 为 [ 在 source 中的位置。
+// 列表项后续行开头的 [ ] 也会被解析为复选框，只有列表项的第一个才是任务
+func eachCheckbox(doc ast.Node, fn func(box *east.TaskCheckBox, offset int)) {
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		box, ok := node.(*east.TaskCheckBox)
+		if !entering || !ok || box.PreviousSibling() != nil {
+			return ast.WalkContinue, nil
+		}
+		if lines := box.Parent().Lines(); lines.Len() > 0 {
+			fn(box, lines.At(0).Start)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// Tasks 正文中的任务，与预览页面使用相同的 Markdown 解析，front matter 及代码块中的不是任务
+func (n *Note) Tasks() []Task {
+	var (
+		body = n.body()
+		base = len(n.Content) - len(body)
+		arr  []Task
+	)
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader([]byte(body)))
+	eachCheckbox(doc, func(box *east.TaskCheckBox, offset int) {
+		line := body[offset:]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		arr = append(arr, Task{Offset: base + offset, Done: box.IsChecked, Text: strings.TrimSpace(line[3:])})
+	})
+	return arr
+}
+
+// openTasks 未完成的任务数量
+func (n *Note) openTasks() int {
+	var count int
+	for _, t := range n.Tasks() {
+		if !t.Done {
+			count++
+		}
+	}
+	return count
+}
+
+// SetTask 修改位于 offset 的任务的完成状态，没有该任务时返回 false
+func (n *Note) SetTask(offset int, done bool) bool {
+	for _, t := range n.Tasks() {
+		if t.Offset != offset {
+			continue
+		}
+		mark := " "
+		if done {
+			mark = "x"
+		}
+		n.Content = n.Content[:offset+1] + mark + n.Content[offset+2:]
+		return true
+	}
+	return false
+}
+
+// taskTransformer 为正文中的复选框加上任务在 Content 中的位置，origin 将 source 中的位置转换为 Content 中的位置
+type taskTransformer struct {
+	origin func(int) (int, bool)
+}
+
+func (t taskTransformer) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	eachCheckbox(doc, func(box *east.TaskCheckBox, offset int) {
+		if v, ok := t.origin(offset); ok {
+			box.SetAttributeString("data-task", []byte(strconv.Itoa(v)))
+		}
+	})
+}
+
+// taskRenderer 有位置的复选框可以勾选，其余的与 GFM 相同
+type taskRenderer struct{}
+
+func (taskRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(east.KindTaskCheckBox, renderTask)
+}
+
+func renderTask(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	box := node.(*east.TaskCheckBox)
+	w.WriteString("<input")
+	if box.IsChecked {
+		w.WriteString(` checked=""`)
+	}
+	if v, ok := box.AttributeString("data-task"); ok {
+		w.WriteString(` data-task="` + string(v.([]byte)) + `"`)
+	} else {
+		w.WriteString(` disabled=""`)
+	}
+	w.WriteString(` type="checkbox" /> `)
+	return ast.WalkContinue, nil
+}
+
+// taskOptions 允许勾选任务时使用的解析及渲染设置，正文位于 source 的 [start, start+len(body)) 之间
+func (n *Note) taskOptions(start, length int, origin func(int) int) []goldmark.Option {
+	valid := make(map[int]bool)
+	for _, t := range n.Tasks() {
+		valid[t.Offset] = true
+	}
+	base := len(n.Content) - len(n.body())
+	return []goldmark.Option{
+		goldmark.WithParserOptions(parser.WithASTTransformers(util.Prioritized(taskTransformer{
+			origin: func(offset int) (int, bool) {
+				if offset < start || offset >= start+length {
+					return 0, false
+				}
+				v := base + origin(offset-start)
+				return v, valid[v]
+			},
+		}, 100))),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(taskRenderer{}, 100))),
+	}
+}
+
+// taskScript 勾选后提交到预览地址下的 /task，失败时恢复
+const taskScript = `<script>
+document.querySelectorAll("input[data-task]").forEach(function (el) {
+	el.addEventListener("change", function () {
+		fetch(location.pathname + "/task", {
+			method: "POST",
+			headers: {"Content-Type": "application/json"},
+			body: JSON.stringify({offset: +el.dataset.task, done: el.checked})
+		}).then(function (r) {
+			if (!r.ok) throw r.status;
+		}).catch(function () {
+			el.checked = !el.checked;
+		});
+	});
+});
+</script>
+`
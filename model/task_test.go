@@ -0,0 +1,85 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNoteTasks(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []Task
+	}{
+		{"- [ ] a\n- [x] b\n- [X] c", []Task{{2, false, "a"}, {10, true, "b"}, {18, true, "c"}}},
+		{"---\ntags: [a]\n---\n- [ ] a", []Task{{20, false, "a"}}},
+		{"text\n\n* [ ] star\n1. [ ] number", []Task{{8, false, "star"}, {20, false, "number"}}},
+		{"- item\n  - [ ] nested", []Task{{11, false, "nested"}}},
+		{"```\n- [ ] code\n```\n- [ ] a", []Task{{21, false, "a"}}},
+		{"    - [ ] indented code", nil},
+		{"- [ ]\n-[ ] a\n[ ] b", []Task{{2, false, ""}}},
+		{"- [ ] a\n[ ] b", []Task{{2, false, "a"}}},
+		{"- [ ] [[Link]] and `code`", []Task{{2, false, "[[Link]] and `code`"}}},
+	}
+	for _, tt := range tests {
+		n := &Note{Content: tt.content}
+		n.ParseMeta()
+		if got := n.Tasks(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tasks(%q) = %+v, want %+v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestNoteSetTask(t *testing.T) {
+	tests := []struct {
+		content string
+		offset  int
+		done    bool
+		want    string
+		ok      bool
+	}{
+		{"- [ ] a\n- [ ] b", 10, true, "- [ ] a\n- [x] b", true},
+		{"- [x] a", 2, false, "- [ ] a", true},
+		{"- [X] a", 2, true, "- [x] a", true},
+		{"---\nt: 1\n---\n- [ ] a", 15, true, "---\nt: 1\n---\n- [x] a", true},
+		{"- [ ] a", 0, true, "- [ ] a", false},
+		{"```\n- [ ] a\n```", 6, true, "```\n- [ ] a\n```", false},
+		{"- [ ] a", 100, true, "- [ ] a", false},
+	}
+	for _, tt := range tests {
+		n := &Note{Content: tt.content}
+		n.ParseMeta()
+		if ok := n.SetTask(tt.offset, tt.done); ok != tt.ok || n.Content != tt.want {
+			t.Errorf("SetTask(%q, %d, %v) = %v, %q, want %v, %q",
+				tt.content, tt.offset, tt.done, ok, n.Content, tt.ok, tt.want)
+		}
+	}
+}
+
+func TestNoteHTMLTasks(t *testing.T) {
+	tests := []struct {
+		content  string
+		editable bool
+		want     []string
+	}{
+		{"- [ ] a\n- [x] b", true, []string{`<input data-task="2" type="checkbox" />`,
+			`<input checked="" data-task="10" type="checkbox" />`, "<script>"}},
+		{"- [ ] [[Missing]] a\n- [ ] b", true, []string{`data-task="2"`, `data-task="22"`}},
+		{"---\nt: 1\n---\n- [ ] a", true, []string{`data-task="15"`}},
+		{"- [ ] a\n[ ] b", true, []string{`data-task="2"`, `<input disabled="" type="checkbox" />`}},
+		{"- [ ] a", false, []string{`<input disabled="" type="checkbox" />`}},
+	}
+	for _, tt := range tests {
+		n := &Note{Title: "t", Content: tt.content, Editable: tt.editable}
+		n.ParseMeta()
+		got := string(n.HTML())
+		for _, v := range tt.want {
+			if !strings.Contains(got, v) {
+				t.Errorf("HTML(%q) = %s, want %s", tt.content, got, v)
+			}
+		}
+		if !tt.editable && strings.Contains(got, "<script>") {
+			t.Errorf("HTML(%q) has script when read-only", tt.content)
+		}
+	}
+}
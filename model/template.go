@@ -0,0 +1,25 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// NoteTemplate 笔记模板，使用时展开占位符后放入草稿箱
+type NoteTemplate struct {
+	ID        uint64    `gorm:"primaryKey" json:"id" `
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name    string `gorm:"uniqueIndex" json:"name"` // 模板名称
+	Content string `json:"content"`                 // 模板内容
+}
+
+// Expand 展开 {{date}} {{time}} {{weekday}} 占位符
+func (t *NoteTemplate) Expand(now time.Time) string {
+	return strings.NewReplacer(
+		"{{date}}", now.Format("2006-01-02"),
+		"{{time}}", now.Format("15:04"),
+		"{{weekday}}", now.Weekday().String(),
+	).Replace(t.Content)
+}
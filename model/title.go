@@ -0,0 +1,121 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+const untitled = "无标题文档"
+
+// NewNote 标题依次取自 /title、front matter 中的 title、开头的 # 标题、
+// 不超过长度的第一行，都没有时使用第一句话
+func NewNote(title, text string) *Note {
+	n := &Note{Title: strings.TrimSpace(title), Content: text}
+	n.ParseMeta()
+	if n.Title != "" {
+		return n
+	}
+	fm, body := ParseFrontMatter(text)
+	if fm != nil && fm.Title != "" {
+		n.Title = fm.Title
+		return n
+	}
+
+	// 标题从 front matter 之后的内容中提取
+	prefix := text[:len(text)-len(body)]
+	if title, content, ok := headingTitle(body); ok {
+		n.Title, n.Content = title, prefix+content
+		return n
+	}
+	str := strings.SplitN(body, "\n", 2)
+	if line := strings.TrimSpace(str[0]); len(str) == 2 && line != "" && !isAttachmentLine(line) &&
+		len([]rune(line)) <= Conf.TitleLength() {
+		n.Title, n.Content = line, prefix+str[1]
+		return n
+	}
+	n.Title = firstSentence(body, Conf.TitleLength())
+	return n
+}
+
+// splitFrontMatter 开头 --- 与 --- 之间的内容
+func splitFrontMatter(text string) (block, rest string, ok bool) {
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return "", text, false
+	}
+	body := text[strings.IndexByte(text, '\n')+1:]
+	for i := 0; i <= len(body); {
+		j := strings.IndexByte(body[i:], '\n')
+		line := body[i:]
+		if j >= 0 {
+			line = body[i : i+j]
+		}
+		if strings.TrimRight(line, " \r") == "---" {
+			if j < 0 {
+				return body[:i], "", true
+			}
+			return body[:i], body[i+j+1:], true
+		}
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return "", text, false
+}
+
+// headingTitle 第一个非空行为 # 标题时，去掉该行作为标题
+func headingTitle(text string) (title, content string, ok bool) {
+	rest := strings.TrimLeft(text, " \t\r\n")
+	line := rest
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		line, content = rest[:i], rest[i+1:]
+	}
+	if !strings.HasPrefix(line, "# ") {
+		return "", text, false
+	}
+	title = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[2:]), "#"))
+	if title == "" {
+		return "", text, false
+	}
+	return title, strings.TrimLeft(content, "\r\n"), true
+}
+
+// firstSentence 第一句话，跳过附件，超过长度的截断
+func firstSentence(text string, length int) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || isAttachmentLine(line) {
+			continue
+		}
+		r := []rune(line)
+		for i, c := range r {
+			if isSentenceEnd(c) && (c != '.' || i+1 == len(r) || unicode.IsSpace(r[i+1])) {
+				r = []rune(strings.TrimSpace(string(r[:i])))
+				break
+			}
+		}
+		if len(r) == 0 {
+			continue
+		}
+		if len(r) > length {
+			return string(r[:length]) + "…"
+		}
+		return string(r)
+	}
+	return untitled
+}
+
+// isAttachmentLine 只有附件链接的行
+func isAttachmentLine(line string) bool {
+	line = strings.TrimSpace(line)
+	return len(Attachments(line)) != 0 && strings.HasPrefix(strings.TrimPrefix(line, "!"), "[") &&
+		strings.HasSuffix(line, ")")
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '。', '！', '？', '；', '!', '?', ';', '.':
+		return true
+	}
+	return false
+}
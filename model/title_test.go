@@ -0,0 +1,39 @@
+package model
+
+import "testing"
+
+func TestNewNote(t *testing.T) {
+	tests := []struct {
+		title, text   string
+		want, content string
+	}{
+		{"  Given ", "line\nbody", "Given", "line\nbody"},
+		{"", "---\ntitle: Meta\n---\n# Heading\nbody", "Meta", "---\ntitle: Meta\n---\n# Heading\nbody"},
+		{"", "\n\n# Heading\nbody", "Heading", "body"},
+		{"", "#tag line\nbody", "#tag line", "body"},
+		{"", "# \nbody", "#", "body"},
+		{"", "  \nbody", "body", "  \nbody"},
+		{"", " Short line \r\nbody", "Short line", "body"},
+		{"", "Short line\nbody", "Short line", "body"},
+		{"", "![a.png](/file/a.png)\n这是第一句。这是第二句", "这是第一句", "![a.png](/file/a.png)\n这是第一句。这是第二句"},
+		{"", "Version 1.2 is out. More text", "Version 1.2 is out", "Version 1.2 is out. More text"},
+		{"", "这是一个没有标点而且超过了三十二个字符长度的很长很长很长很长很长的句子\n下一行",
+			"这是一个没有标点而且超过了三十二个字符长度的很长很长很长很长很长…",
+			"这是一个没有标点而且超过了三十二个字符长度的很长很长很长很长很长的句子\n下一行"},
+		{"", "single line", "single line", "single line"},
+		{"", "##  Sub heading\nbody", "##  Sub heading", "body"},
+		{"", "#NoSpace\nbody", "#NoSpace", "body"},
+		{"", "# Heading\r\n\r\nbody", "Heading", "body"},
+		{"", "Mr. Smith said hi\nabout it", "Mr. Smith said hi", "about it"},
+		{"", "[doc.pdf](/file/a.pdf)\n", "无标题文档", "[doc.pdf](/file/a.pdf)\n"},
+		{"", "。\n", "。", ""},
+		{"", "。", "无标题文档", "。"},
+		{"", "", "无标题文档", ""},
+	}
+	for _, tt := range tests {
+		n := NewNote(tt.title, tt.text)
+		if n.Title != tt.want || n.Content != tt.content {
+			t.Errorf("NewNote(%q, %q) = %q, %q, want %q, %q", tt.title, tt.text, n.Title, n.Content, tt.want, tt.content)
+		}
+	}
+}
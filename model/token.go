@@ -25,6 +25,8 @@ func (t Token) Valid() bool {
 	case Share:
 		return Conf.Token.Share == 0 ||
 			t.Time.Add(time.Duration(Conf.Token.Share)*time.Minute).After(time.Now())
+	case Graph:
+		return t.Time.Add(time.Duration(Conf.Token.Graph) * time.Minute).After(time.Now())
 	}
 	return false
 }
@@ -36,6 +38,7 @@ const (
 	Preview
 	View
 	Share
+	Graph // 笔记关系图
 )
 
 func NewToken(t Type, noteID uint64) Token {
@@ -0,0 +1,131 @@
+package document
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTextSize 纯文本最多读取的字节数
+const maxTextSize = 1 << 20
+
+var ErrUnsupported = errors.New("document: unsupported format")
+
+// Extractor 提取文档中的文字，支持 PDF、DOCX 及纯文本
+type Extractor struct {
+	PDFToText string // pdftotext 可执行文件路径，为空不提取 PDF
+}
+
+// Supported 是否支持该扩展名
+func (e *Extractor) Supported(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".txt", ".md", ".markdown", ".docx":
+		return true
+	case ".pdf":
+		return e.PDFToText != ""
+	}
+	return false
+}
+
+// Extract 按扩展名提取文字，ext 为空时使用 filename 的扩展名
+func (e *Extractor) Extract(ctx context.Context, filename, ext string) (string, error) {
+	if ext == "" {
+		ext = filepath.Ext(filename)
+	}
+	switch strings.ToLower(ext) {
+	case ".txt", ".md", ".markdown":
+		return plain(filename)
+	case ".docx":
+		return docx(filename)
+	case ".pdf":
+		if e.PDFToText != "" {
+			return e.pdf(ctx, filename)
+		}
+	}
+	return "", ErrUnsupported
+}
+
+func (e *Extractor) pdf(ctx context.Context, filename string) (string, error) {
+	out, err := exec.CommandContext(ctx, e.PDFToText, "-enc", "UTF-8", "-q", filename, "-").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func plain(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(f, maxTextSize))
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(buf) {
+		return "", ErrUnsupported
+	}
+	return strings.TrimSpace(string(buf)), nil
+}
+
+// docx 读取 word/document.xml 中 w:t 的内容，w:p 作为换行
+func docx(filename string) (string, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+
+		var (
+			sb     strings.Builder
+			inText bool
+			dec    = xml.NewDecoder(rc)
+		)
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				inText = t.Name.Local == "t"
+				if t.Name.Local == "tab" {
+					sb.WriteByte('\t')
+				}
+			case xml.EndElement:
+				inText = false
+				if t.Name.Local == "p" {
+					sb.WriteByte('\n')
+				}
+			case xml.CharData:
+				if inText {
+					sb.Write(t)
+				}
+			}
+		}
+		return strings.TrimSpace(sb.String()), nil
+	}
+	return "", ErrUnsupported
+}
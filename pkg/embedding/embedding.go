@@ -0,0 +1,129 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Embedder 文本转为向量
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// HTTP 调用 OpenAI 风格的 /v1/embeddings 接口，
+// 本地的 llama.cpp server、Ollama 及 text-embeddings-inference 都兼容
+type HTTP struct {
+	Endpoint string
+	Model    string
+	Key      string
+	Client   *http.Client
+}
+
+func NewHTTP(endpoint, model, key string) *HTTP {
+	return &HTTP{
+		Endpoint: endpoint,
+		Model:    model,
+		Key:      key,
+		Client:   &http.Client{Timeout: time.Minute},
+	}
+}
+
+type request struct {
+	Input []string `json:"input"`
+	Model string   `json:"model,omitempty"`
+}
+
+type response struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (h *HTTP) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(&request{Input: texts, Model: h.Model})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Key)
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("embedding: status %d: %w", resp.StatusCode, err)
+	}
+	if r.Error != nil {
+		return nil, errors.New("embedding: " + r.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding: status %d", resp.StatusCode)
+	}
+	if len(r.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding: got %d vectors for %d texts", len(r.Data), len(texts))
+	}
+
+	arr := make([][]float32, len(texts))
+	for i, v := range r.Data {
+		if v.Index >= 0 && v.Index < len(arr) {
+			i = v.Index
+		}
+		arr[i] = v.Embedding
+	}
+	return arr, nil
+}
+
+// Cosine 余弦相似度，长度不同或为零向量时返回 0
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Encode 以 float32 小端序保存
+func Encode(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+func Decode(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
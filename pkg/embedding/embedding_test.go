@@ -0,0 +1,48 @@
+package embedding
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 0}, []float32{1, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 2}, []float32{-1, -2}, -1},
+		{[]float32{1, 1}, []float32{2, 2}, 1},
+		{[]float32{3, 4}, []float32{4, 3}, 0.96},
+		{[]float32{0, 0}, []float32{1, 1}, 0},
+		{[]float32{1, 0}, []float32{1, 0, 0}, 0},
+		{nil, nil, 0},
+	}
+	for _, tt := range tests {
+		if got := Cosine(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("Cosine(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		v    []float32
+		want []byte
+	}{
+		{[]float32{1}, []byte{0, 0, 0x80, 0x3f}},
+		{[]float32{-2, 0.5}, []byte{0, 0, 0, 0xc0, 0, 0, 0, 0x3f}},
+		{[]float32{}, []byte{}},
+	}
+	for _, tt := range tests {
+		b := Encode(tt.v)
+		if !reflect.DeepEqual(b, tt.want) {
+			t.Errorf("Encode(%v) = %v, want %v", tt.v, b, tt.want)
+		}
+		if got := Decode(b); !reflect.DeepEqual(got, tt.v) {
+			t.Errorf("Decode(%v) = %v, want %v", b, got, tt.v)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package index
+
+import (
+	"encoding/gob"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/x2ox/memo/pkg/query"
+)
+
+const (
+	FieldTitle = iota
+	FieldContent
+	FieldAttachment
+	FieldCount
+)
+
+// BM25F 的参数，各字段的词频按权重合并后计算
+var (
+	fieldWeight = [FieldCount]float64{3, 1, 0.5}
+	k1          = 1.2
+	b           = 0.75
+)
+
+// expandWeight 前缀及模糊匹配到的词，得分低于精确匹配
+const expandWeight = 0.5
+
+type Doc struct {
+	Length  [FieldCount]int
+	Tags    []string
+	Created time.Time
+	Updated time.Time // 建立索引时笔记的修改时间，用于检查索引是否过期
+}
+
+// Index 保存在磁盘上的倒排索引，全部加载到内存中使用
+type Index struct {
+	mux  sync.RWMutex
+	path string
+
+	Docs  map[uint64]*Doc
+	Terms map[string]map[uint64][FieldCount]int
+}
+
+// Open 读取索引文件，文件不存在时创建空的索引
+func Open(path string) (*Index, error) {
+	idx := &Index{path: path}
+	idx.reset()
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err = gob.NewDecoder(f).Decode(idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (idx *Index) reset() {
+	idx.Docs = make(map[uint64]*Doc)
+	idx.Terms = make(map[string]map[uint64][FieldCount]int)
+}
+
+// Reset 清空索引
+func (idx *Index) Reset() {
+	idx.mux.Lock()
+	idx.reset()
+	idx.mux.Unlock()
+}
+
+// Save 先写入临时文件再替换，避免写入中断损坏索引
+func (idx *Index) Save() error {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(idx.path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := idx.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err = gob.NewEncoder(f).Encode(idx); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+func (idx *Index) Len() int {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	return len(idx.Docs)
+}
+
+// Matches 索引中的文档与 updated 中的 id 及修改时间完全一致
+func (idx *Index) Matches(updated map[uint64]time.Time) bool {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+
+	if len(idx.Docs) != len(updated) {
+		return false
+	}
+	for id, t := range updated {
+		doc, ok := idx.Docs[id]
+		if !ok || !doc.Updated.Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Put 写入文档，已存在时替换
+func (idx *Index) Put(id uint64, doc Doc, fields [FieldCount][]string) {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+
+	idx.remove(id)
+	for field, tokens := range fields {
+		doc.Length[field] = len(tokens)
+		for _, v := range tokens {
+			v = strings.ToLower(v)
+			if idx.Terms[v] == nil {
+				idx.Terms[v] = make(map[uint64][FieldCount]int)
+			}
+			tf := idx.Terms[v][id]
+			tf[field]++
+			idx.Terms[v][id] = tf
+		}
+	}
+	idx.Docs[id] = &doc
+}
+
+func (idx *Index) Remove(id uint64) {
+	idx.mux.Lock()
+	idx.remove(id)
+	idx.mux.Unlock()
+}
+
+func (idx *Index) remove(id uint64) {
+	if _, ok := idx.Docs[id]; !ok {
+		return
+	}
+	delete(idx.Docs, id)
+	for term, m := range idx.Terms {
+		if _, ok := m[id]; ok {
+			delete(m, id)
+			if len(m) == 0 {
+				delete(idx.Terms, term)
+			}
+		}
+	}
+}
+
+type Hit struct {
+	ID         uint64
+	Score      float64
+	Attachment bool // 附件中的文字有匹配
+}
+
+// Facets 按标签及月份统计命中的数量
+type Facets struct {
+	Tags   map[string]int
+	Months map[string]int
+}
+
+// Search 返回按 BM25F 排序的全部结果，短语只按词匹配，需要调用方验证
+func (idx *Index) Search(q *query.Query) ([]Hit, Facets) {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+
+	facets := Facets{Tags: make(map[string]int), Months: make(map[string]int)}
+	if !q.HasText() {
+		return nil, facets
+	}
+
+	var (
+		must = make([][]expanded, len(q.Must))
+		not  = make([]expanded, len(q.Not))
+	)
+	for i, group := range q.Must {
+		for _, t := range group {
+			must[i] = append(must[i], idx.expand(t))
+		}
+	}
+	for i, t := range q.Not {
+		not[i] = idx.expand(t)
+	}
+
+	avg := idx.avgLength()
+	var hits []Hit
+	for id, doc := range idx.candidates(must[0]) {
+		if !idx.filter(q, doc) {
+			continue
+		}
+
+		hit, ok := Hit{ID: id}, true
+		for i, group := range must {
+			matched := false
+			for j, t := range group {
+				if !idx.has(q.Must[i][j], t, id) {
+					continue
+				}
+				matched = true
+				score, attachment := idx.score(t, id, doc, avg)
+				hit.Score += score
+				hit.Attachment = hit.Attachment || attachment
+			}
+			if !matched {
+				ok = false
+				break
+			}
+		}
+		for i, t := range not {
+			if ok && !q.Not[i].Phrase && idx.has(q.Not[i], t, id) {
+				ok = false
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		hits = append(hits, hit)
+		for _, v := range doc.Tags {
+			facets.Tags[v]++
+		}
+		facets.Months[doc.Created.Format("2006-01")]++
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	return hits, facets
+}
+
+// expanded 每个分词扩展后的词及权重
+type expanded []map[string]float64
+
+// expand 前缀匹配最后一个词，模糊匹配所有的词
+func (idx *Index) expand(t query.Term) expanded {
+	arr := make(expanded, len(t.Tokens))
+	for i, v := range t.Tokens {
+		v = strings.ToLower(v)
+		arr[i] = make(map[string]float64)
+		if _, ok := idx.Terms[v]; ok {
+			arr[i][v] = 1
+		}
+
+		prefix := t.Prefix && i == len(t.Tokens)-1
+		if !prefix && t.Fuzzy == 0 {
+			continue
+		}
+		for term := range idx.Terms {
+			if term == v {
+				continue
+			}
+			if (prefix && strings.HasPrefix(term, v)) ||
+				(t.Fuzzy > 0 && levenshtein(term, v, t.Fuzzy) <= t.Fuzzy) {
+				arr[i][term] = expandWeight
+			}
+		}
+	}
+	return arr
+}
+
+// candidates 包含第一组中任意词的文档
+func (idx *Index) candidates(group []expanded) map[uint64]*Doc {
+	m := make(map[uint64]*Doc)
+	for _, t := range group {
+		for _, terms := range t {
+			for term := range terms {
+				for id := range idx.Terms[term] {
+					m[id] = idx.Docs[id]
+				}
+			}
+		}
+	}
+	return m
+}
+
+// has 每个分词至少有一个扩展后的词出现在文档中，限定标题时只看标题
+func (idx *Index) has(t query.Term, e expanded, id uint64) bool {
+	for _, terms := range e {
+		found := false
+		for term := range terms {
+			tf, ok := idx.Terms[term][id]
+			if ok && (t.Field != query.FieldTitle || tf[FieldTitle] > 0) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (idx *Index) score(e expanded, id uint64, doc *Doc, avg [FieldCount]float64) (score float64, attachment bool) {
+	n := float64(len(idx.Docs))
+	for _, terms := range e {
+		for term, weight := range terms {
+			tf, ok := idx.Terms[term][id]
+			if !ok {
+				continue
+			}
+			attachment = attachment || tf[FieldAttachment] > 0
+
+			var w float64
+			for field := range tf {
+				if avg[field] == 0 {
+					continue
+				}
+				norm := 1 - b + b*float64(doc.Length[field])/avg[field]
+				w += fieldWeight[field] * float64(tf[field]) / norm
+			}
+			df := float64(len(idx.Terms[term]))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += weight * idf * w * (k1 + 1) / (w + k1)
+		}
+	}
+	return
+}
+
+func (idx *Index) avgLength() [FieldCount]float64 {
+	var avg [FieldCount]float64
+	if len(idx.Docs) == 0 {
+		return avg
+	}
+	for _, doc := range idx.Docs {
+		for field := range avg {
+			avg[field] += float64(doc.Length[field])
+		}
+	}
+	for field := range avg {
+		avg[field] /= float64(len(idx.Docs))
+	}
+	return avg
+}
+
+// filter 标签及日期
+func (idx *Index) filter(q *query.Query, doc *Doc) bool {
+	if doc == nil {
+		return false
+	}
+	if !q.Before.IsZero() && !doc.Created.Before(q.Before) {
+		return false
+	}
+	if !q.After.IsZero() && doc.Created.Before(q.After) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		found := false
+		for _, v := range doc.Tags {
+			if strings.EqualFold(v, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, tag := range q.NotTags {
+		for _, v := range doc.Tags {
+			if strings.EqualFold(v, tag) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// levenshtein 编辑距离，超过 max 时提前返回
+func levenshtein(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		least := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if cur[j] < least {
+				least = cur[j]
+			}
+		}
+		if least > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(arr ...int) int {
+	m := arr[0]
+	for _, v := range arr[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
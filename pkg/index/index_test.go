@@ -0,0 +1,162 @@
+package index
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/x2ox/memo/pkg/participle"
+	"github.com/x2ox/memo/pkg/query"
+)
+
+func day(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+	return t
+}
+
+func newIndex(t *testing.T) *Index {
+	idx, err := Open(filepath.Join(t.TempDir(), "search.gob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		id                         uint64
+		title, content, attachment string
+		tags                       []string
+		created                    string
+	}{
+		{1, "Go memo", "search index notes", "", []string{"work"}, "2026-09-01"},
+		{2, "Shopping", "memo milk eggs", "", []string{"home"}, "2026-10-05"},
+		{3, "Meeting", "roadmap review", "memo scan", []string{"Work"}, "2026-10-10"},
+	} {
+		idx.Put(v.id, Doc{Tags: v.tags, Created: day(v.created), Updated: day(v.created)}, [FieldCount][]string{
+			participle.Tokens(v.title), participle.Tokens(v.content), participle.Tokens(v.attachment),
+		})
+	}
+	return idx
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := newIndex(t)
+	tests := []struct {
+		in   string
+		want []uint64 // 按 ID 排列
+	}{
+		{"memo", []uint64{1, 2, 3}},
+		{"MEMO", []uint64{1, 2, 3}},
+		{"memo -milk", []uint64{1, 3}},
+		{"title:memo", []uint64{1}},
+		{"memo tag:work", []uint64{1, 3}},
+		{"memo -tag:work", []uint64{2}},
+		{"memo after:2026-10-01", []uint64{2, 3}},
+		{"memo before:2026-10-01", []uint64{1}},
+		{"milk OR roadmap", []uint64{2, 3}},
+		{"memo milk", []uint64{2}},
+		{`"eggs milk"`, []uint64{2}},
+		{"mem*", []uint64{1, 2, 3}},
+		{"road*", []uint64{3}},
+		{"meno~", []uint64{1, 2, 3}},
+		{"meno", nil},
+		{"notes", []uint64{1}},
+		{"tag:work", nil},
+		{"-memo", nil},
+		{"mm~2", []uint64{1, 2, 3}},
+		{"memo -\"milk eggs\"", []uint64{1, 2, 3}}, // 排除的短语由调用方验证
+		{"", nil},
+	}
+	for _, tt := range tests {
+		hits, _ := idx.Search(query.Parse(tt.in))
+		var got []uint64
+		for _, v := range hits {
+			got = append(got, v.ID)
+		}
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIndexRank(t *testing.T) {
+	idx := newIndex(t)
+	hits, facets := idx.Search(query.Parse("memo"))
+	if len(hits) != 3 || hits[0].ID != 1 || hits[1].ID != 2 || hits[2].ID != 3 {
+		t.Fatalf("Search(memo) = %+v, want title, content, attachment", hits)
+	}
+	if hits[0].Attachment || hits[1].Attachment || !hits[2].Attachment {
+		t.Errorf("Search(memo) attachment = %+v", hits)
+	}
+	if want := map[string]int{"work": 1, "home": 1, "Work": 1}; !reflect.DeepEqual(facets.Tags, want) {
+		t.Errorf("Facets.Tags = %v, want %v", facets.Tags, want)
+	}
+	if want := map[string]int{"2026-09": 1, "2026-10": 2}; !reflect.DeepEqual(facets.Months, want) {
+		t.Errorf("Facets.Months = %v, want %v", facets.Months, want)
+	}
+
+	exact, _ := idx.Search(query.Parse("roadmap"))
+	prefix, _ := idx.Search(query.Parse("roadma*"))
+	if len(exact) != 1 || len(prefix) != 1 || prefix[0].Score >= exact[0].Score {
+		t.Errorf("prefix %+v should score below exact %+v", prefix, exact)
+	}
+}
+
+func TestIndexPersist(t *testing.T) {
+	idx := newIndex(t)
+	idx.Remove(2)
+	idx.Put(3, Doc{Updated: day("2026-10-11")}, [FieldCount][]string{{"meet"}})
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Open(idx.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		updated map[uint64]time.Time
+		want    bool
+	}{
+		{map[uint64]time.Time{1: day("2026-09-01"), 3: day("2026-10-11")}, true},
+		{map[uint64]time.Time{1: day("2026-09-01"), 3: day("2026-10-10")}, false},
+		{map[uint64]time.Time{1: day("2026-09-01")}, false},
+		{map[uint64]time.Time{1: day("2026-09-01"), 2: day("2026-10-05"), 3: day("2026-10-11")}, false},
+	}
+	for _, tt := range tests {
+		if v := got.Matches(tt.updated); v != tt.want {
+			t.Errorf("Matches(%v) = %v, want %v", tt.updated, v, tt.want)
+		}
+	}
+	if _, ok := got.Terms["milk"]; ok {
+		t.Error("terms of removed note are kept")
+	}
+	if _, ok := got.Terms["roadmap"]; ok {
+		t.Error("terms of replaced note are kept")
+	}
+	if hits, _ := got.Search(query.Parse("meet")); len(hits) != 1 || hits[0].ID != 3 {
+		t.Errorf("Search(meet) = %+v", hits)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"memo", "memo", 1, 0},
+		{"memo", "meno", 1, 1},
+		{"memo", "mem", 1, 1},
+		{"memo", "demos", 2, 2},
+		{"memo", "note", 1, 2},
+		{"memo", "m", 1, 2},
+		{"笔记", "笔迹", 1, 1},
+		{"", "ab", 2, 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b, tt.max); got != tt.want {
+			t.Errorf("levenshtein(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.max, got, tt.want)
+		}
+	}
+}
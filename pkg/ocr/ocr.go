@@ -0,0 +1,58 @@
+package ocr
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/x2ox/memo/pkg/util"
+)
+
+// Recognizer 识别图片中的文字
+type Recognizer interface {
+	Recognize(ctx context.Context, filename string) (string, error)
+}
+
+// Tesseract 调用本地的 tesseract 可执行文件
+type Tesseract struct {
+	Path     string
+	Language string
+}
+
+func NewTesseract(path, language string) *Tesseract {
+	if language == "" {
+		language = "chi_sim+eng"
+	}
+	return &Tesseract{Path: path, Language: language}
+}
+
+func (t *Tesseract) Recognize(ctx context.Context, filename string) (string, error) {
+	out, err := exec.CommandContext(ctx, t.Path, filename, "stdout", "-l", t.Language).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// HTTP 调用 OCR 服务，上传字段为 file，返回 {"text": "..."}
+type HTTP struct {
+	Endpoint string
+	Language string
+	Client   *http.Client
+}
+
+func NewHTTP(endpoint, language string) *HTTP {
+	return &HTTP{
+		Endpoint: endpoint,
+		Language: language,
+		Client:   &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (h *HTTP) Recognize(ctx context.Context, filename string) (string, error) {
+	return util.PostFile(ctx, h.Client, h.Endpoint, filename, map[string]string{
+		"language": h.Language,
+	})
+}
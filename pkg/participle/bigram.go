@@ -0,0 +1,11 @@
+//go:build !cgo
+// +build !cgo
+
+package participle
+
+// Init 不使用 CGO 时没有词典，中文使用 Bigram 分词
+func Init(string) {}
+
+func cutHan(s string) []string { return Bigram(s) }
+
+func reload() error { return nil }
@@ -0,0 +1,144 @@
+package participle
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoDict 没有使用 jieba 分词，不支持用户词典
+var ErrNoDict = errors.New("user dict is not supported")
+
+// Word 用户词典中的词，每行格式为「词」「词 词性」或「词 词频 词性」
+type Word struct {
+	Word string
+	Freq int    // 为零时使用 jieba 的默认权重
+	Tag  string // 词性，如 n nz
+}
+
+// defaultTag 指定了词频而没有词性时使用，jieba 只接受三列中的词频
+const defaultTag = "nz"
+
+func (w Word) String() string {
+	switch {
+	case w.Freq > 0 && w.Tag == "":
+		return w.Word + " " + strconv.Itoa(w.Freq) + " " + defaultTag
+	case w.Freq > 0:
+		return w.Word + " " + strconv.Itoa(w.Freq) + " " + w.Tag
+	case w.Tag != "":
+		return w.Word + " " + w.Tag
+	}
+	return w.Word
+}
+
+var (
+	userDict string // 用户词典的路径，在 Init 中设置
+	dictMux  sync.Mutex
+)
+
+// Words 用户词典中的全部词
+func Words() ([]Word, error) {
+	dictMux.Lock()
+	defer dictMux.Unlock()
+	return readWords()
+}
+
+// AddWord 添加词，已存在时更新词频及词性，完成后重新加载分词
+func AddWord(w Word) error {
+	dictMux.Lock()
+	defer dictMux.Unlock()
+
+	arr, err := readWords()
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range arr {
+		if arr[i].Word == w.Word {
+			arr[i], found = w, true
+		}
+	}
+	if !found {
+		arr = append(arr, w)
+	}
+	return writeWords(arr)
+}
+
+// RemoveWord 删除词，不存在时返回 false
+func RemoveWord(word string) (bool, error) {
+	dictMux.Lock()
+	defer dictMux.Unlock()
+
+	arr, err := readWords()
+	if err != nil {
+		return false, err
+	}
+	words := arr[:0]
+	for _, v := range arr {
+		if v.Word != word {
+			words = append(words, v)
+		}
+	}
+	if len(words) == len(arr) {
+		return false, nil
+	}
+	return true, writeWords(words)
+}
+
+func readWords() ([]Word, error) {
+	if userDict == "" {
+		return nil, ErrNoDict
+	}
+	f, err := os.Open(userDict)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var arr []Word
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		w := Word{Word: fields[0]}
+		switch len(fields) {
+		case 2:
+			w.Tag = fields[1]
+		case 3:
+			w.Freq, _ = strconv.Atoi(fields[1])
+			w.Tag = fields[2]
+		}
+		arr = append(arr, w)
+	}
+	return arr, sc.Err()
+}
+
+// writeWords 先写入临时文件再替换，之后重新加载分词
+func writeWords(arr []Word) error {
+	if err := os.MkdirAll(filepath.Dir(userDict), os.ModePerm); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for _, v := range arr {
+		sb.WriteString(v.String())
+		sb.WriteByte('\n')
+	}
+	tmp := userDict + ".tmp"
+	if err := os.WriteFile(tmp, []byte(sb.String()), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, userDict); err != nil {
+		return err
+	}
+	return reload()
+}
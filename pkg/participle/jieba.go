@@ -0,0 +1,55 @@
+//go:build cgo
+// +build cgo
+
+package participle
+
+import (
+	"path"
+	"sync"
+
+	"github.com/yanyiwu/gojieba"
+)
+
+// jieba 加载词典很慢，只创建一次，分词是只读的可以并发调用
+var (
+	jieba *gojieba.Jieba
+	mux   sync.RWMutex
+)
+
+func Init(folderName string) {
+	gojieba.DICT_DIR = path.Join(path.Dir(folderName), "dict")
+	gojieba.DICT_PATH = path.Join(gojieba.DICT_DIR, "jieba.dict.utf8")
+	gojieba.HMM_PATH = path.Join(gojieba.DICT_DIR, "hmm_model.utf8")
+	gojieba.USER_DICT_PATH = path.Join(gojieba.DICT_DIR, "user.dict.utf8")
+	gojieba.IDF_PATH = path.Join(gojieba.DICT_DIR, "idf.utf8")
+	gojieba.STOP_WORDS_PATH = path.Join(gojieba.DICT_DIR, "stop_words.utf8")
+	userDict = gojieba.USER_DICT_PATH
+
+	_ = reload()
+}
+
+// reload 重新加载词典，替换后释放旧的实例
+func reload() error {
+	x := gojieba.NewJieba()
+
+	mux.Lock()
+	old := jieba
+	jieba = x
+	mux.Unlock()
+
+	if old != nil {
+		old.Free()
+	}
+	return nil
+}
+
+// cutHan 中文使用 jieba 的搜索引擎模式分词
+func cutHan(s string) []string {
+	mux.RLock()
+	defer mux.RUnlock()
+
+	if jieba == nil {
+		return Bigram(s)
+	}
+	return jieba.CutForSearch(s, true)
+}
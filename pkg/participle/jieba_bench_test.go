@@ -0,0 +1,34 @@
+//go:build cgo
+// +build cgo
+
+package participle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yanyiwu/gojieba"
+)
+
+const benchText = "今天在图书馆读了一本关于分布式系统的书，顺便整理了 Go 的 memo notes"
+
+// BenchmarkParse 每次创建 jieba 与共享实例的对比
+func BenchmarkParse(b *testing.B) {
+	b.Run("PerCall", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			x := gojieba.NewJieba()
+			_ = strings.Join(x.CutForSearch(benchText, true), " ")
+			x.Free()
+		}
+	})
+
+	b.Run("Shared", func(b *testing.B) {
+		if err := reload(); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = Parse(benchText)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package participle
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Bigram 不依赖词典的分词，中日韩文字输出单字及相邻的双字，其余的按单词切分并转为小写
+func Bigram(s string) []string {
+	var (
+		arr  []string
+		word []rune
+		cjk  []rune
+	)
+	flushWord := func() {
+		if len(word) > 0 {
+			arr = append(arr, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i := range cjk {
+			arr = append(arr, string(cjk[i]))
+			if i+1 < len(cjk) {
+				arr = append(arr, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return arr
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || isProlonged(r)
+}
@@ -0,0 +1,111 @@
+package participle
+
+import (
+	"strings"
+	"unicode"
+)
+
+type script uint8
+
+const (
+	scriptNone script = iota
+	scriptHan         // 只有汉字，按中文处理
+	scriptKana        // 含有假名或谚文，按日文、韩文处理
+	scriptWord        // 其余的字母及数字
+)
+
+type segment struct {
+	script script
+	text   string
+}
+
+// Parse 按文字种类切分后分别分词，建立索引及搜索时都使用，结果以空格连接
+//
+//	中文      jieba，没有 CGO 时使用 Bigram
+//	日文、韩文 单字及双字
+//	英文      小写，去掉停用词并提取词干
+func Parse(s string) string { return strings.Join(Tokens(s), " ") }
+
+func Tokens(s string) []string { return tokens(s, true) }
+
+// Unstemmed 与 Tokens 相同，但英文不提取词干，用于补全还没有输入完的词
+func Unstemmed(s string) []string { return tokens(s, false) }
+
+func tokens(s string, stem bool) []string {
+	var arr []string
+	for _, v := range segments(s) {
+		switch v.script {
+		case scriptHan:
+			arr = append(arr, cutHan(v.text)...)
+		case scriptKana:
+			arr = append(arr, Bigram(v.text)...)
+		case scriptWord:
+			if w, ok := fold(v.text); ok && stem {
+				arr = append(arr, Stem(w))
+			} else if ok {
+				arr = append(arr, w)
+			}
+		}
+	}
+	return arr
+}
+
+// segments 连续的中日韩文字为一段，其中有假名或谚文时视为日文、韩文
+func segments(s string) []segment {
+	var (
+		arr []segment
+		rs  = []rune(s)
+	)
+	for i := 0; i < len(rs); {
+		j, seg := i, segment{}
+		switch {
+		case isCJK(rs[i]):
+			seg.script = scriptHan
+			for ; j < len(rs) && isCJK(rs[j]); j++ {
+				if !unicode.Is(unicode.Han, rs[j]) {
+					seg.script = scriptKana
+				}
+			}
+		case isWord(rs[i]):
+			seg.script = scriptWord
+			for ; j < len(rs); j++ {
+				if isWord(rs[j]) && !isCJK(rs[j]) {
+					continue
+				}
+				// 单词中间的撇号，如 don't
+				if isApostrophe(rs[j]) && j > i && j+1 < len(rs) && unicode.IsLetter(rs[j+1]) && !isCJK(rs[j+1]) {
+					continue
+				}
+				break
+			}
+		default:
+			i++
+			continue
+		}
+		seg.text = string(rs[i:j])
+		arr = append(arr, seg)
+		i = j
+	}
+	return arr
+}
+
+// fold 转为小写，英文去掉停用词及所有格
+func fold(s string) (string, bool) {
+	s = strings.ToLower(strings.ReplaceAll(s, "’", "'"))
+	if isStopWord(s) {
+		return "", false
+	}
+	s = strings.TrimSuffix(s, "'s")
+	s = strings.ReplaceAll(s, "'", "")
+	if s == "" || isStopWord(s) {
+		return "", false
+	}
+	return s, true
+}
+
+func isWord(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+
+func isApostrophe(r rune) bool { return r == '\'' || r == '’' }
+
+// isProlonged 片假名的长音符号不属于假名
+func isProlonged(r rune) bool { return r == 'ー' || r == 'ｰ' }
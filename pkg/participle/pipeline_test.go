@@ -0,0 +1,74 @@
+package participle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		in        string
+		tokens    []string
+		unstemmed []string
+	}{
+		{"Running runners", []string{"run", "runner"}, []string{"running", "runners"}},
+		{"runn", []string{"runn"}, []string{"runn"}},
+		{"the", nil, nil},
+		{"The cat and the hat", []string{"cat", "hat"}, []string{"cat", "hat"}},
+		{"don't stop John's dog", []string{"stop", "john", "dog"}, []string{"stop", "john", "dog"}},
+		{"it’s Alice’s", []string{"alic"}, []string{"alice"}},
+		{"rock'n'roll 'quoted'", []string{"rocknrol", "quot"}, []string{"rocknroll", "quoted"}},
+		{"Go1.16 v2", []string{"go1", "16", "v2"}, []string{"go1", "16", "v2"}},
+		{"Café Ünïcode", []string{"café", "ünïcode"}, []string{"café", "ünïcode"}},
+		{"カタカナ", []string{"カ", "カタ", "タ", "タカ", "カ", "カナ", "ナ"}, []string{"カ", "カタ", "タ", "タカ", "カ", "カナ", "ナ"}},
+		{"한국", []string{"한", "한국", "국"}, []string{"한", "한국", "국"}},
+		{"... !!", nil, nil},
+		{"", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := Tokens(tt.in); !reflect.DeepEqual(got, tt.tokens) {
+			t.Errorf("Tokens(%q) = %q, want %q", tt.in, got, tt.tokens)
+		}
+		if got := Unstemmed(tt.in); !reflect.DeepEqual(got, tt.unstemmed) {
+			t.Errorf("Unstemmed(%q) = %q, want %q", tt.in, got, tt.unstemmed)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []segment
+	}{
+		{"hello世界", []segment{{scriptWord, "hello"}, {scriptHan, "世界"}}},
+		{"東京へ行く", []segment{{scriptKana, "東京へ行く"}}},
+		{"コーヒー and 茶", []segment{{scriptKana, "コーヒー"}, {scriptWord, "and"}, {scriptHan, "茶"}}},
+		{"서울 Seoul", []segment{{scriptKana, "서울"}, {scriptWord, "Seoul"}}},
+		{"don't, 'x'", []segment{{scriptWord, "don't"}, {scriptWord, "x"}}},
+		{"a'世", []segment{{scriptWord, "a"}, {scriptHan, "世"}}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := segments(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("segments(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBigram(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"中文分词", []string{"中", "中文", "文", "文分", "分", "分词", "词"}},
+		{"Go语言", []string{"go", "语", "语言", "言"}},
+		{"你好, World 2", []string{"你", "你好", "好", "world", "2"}},
+		{"字", []string{"字"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := Bigram(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Bigram(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
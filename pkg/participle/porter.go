@@ -0,0 +1,259 @@
+package participle
+
+// Stem Porter 词干提取，只处理小写的英文字母，其余的原样返回
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	z := &stemmer{b: []byte(word), k: len(word) - 1}
+	z.step1ab()
+	if z.k > 0 {
+		z.step1c()
+		z.step2()
+		z.step3()
+		z.step4()
+		z.step5()
+	}
+	return string(z.b[:z.k+1])
+}
+
+// stemmer b[0..k] 为当前的词，j 为 ends 匹配到的词干的末尾
+type stemmer struct {
+	b    []byte
+	k, j int
+}
+
+func (z *stemmer) cons(i int) bool {
+	switch z.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !z.cons(i-1)
+	}
+	return true
+}
+
+// m b[0..j] 中「辅音 元音」序列的数量
+func (z *stemmer) m() int {
+	n, i := 0, 0
+	for ; ; i++ {
+		if i > z.j {
+			return n
+		}
+		if !z.cons(i) {
+			break
+		}
+	}
+	i++
+	for {
+		for ; ; i++ {
+			if i > z.j {
+				return n
+			}
+			if z.cons(i) {
+				break
+			}
+		}
+		i++
+		n++
+		for ; ; i++ {
+			if i > z.j {
+				return n
+			}
+			if !z.cons(i) {
+				break
+			}
+		}
+		i++
+	}
+}
+
+func (z *stemmer) vowelInStem() bool {
+	for i := 0; i <= z.j; i++ {
+		if !z.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (z *stemmer) doubleC(i int) bool {
+	return i >= 1 && z.b[i] == z.b[i-1] && z.cons(i)
+}
+
+// cvc 以「辅音 元音 辅音」结尾，且最后的辅音不是 w x y
+func (z *stemmer) cvc(i int) bool {
+	if i < 2 || !z.cons(i) || z.cons(i-1) || !z.cons(i-2) {
+		return false
+	}
+	switch z.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func (z *stemmer) ends(s string) bool {
+	if len(s) > z.k+1 || string(z.b[z.k-len(s)+1:z.k+1]) != s {
+		return false
+	}
+	z.j = z.k - len(s)
+	return true
+}
+
+func (z *stemmer) setTo(s string) {
+	z.b = append(z.b[:z.j+1], s...)
+	z.k = z.j + len(s)
+}
+
+// replace 匹配到第一个后缀时，m > 0 才替换
+func (z *stemmer) replace(pairs ...string) {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if z.ends(pairs[i]) {
+			if z.m() > 0 {
+				z.setTo(pairs[i+1])
+			}
+			return
+		}
+	}
+}
+
+// step1ab 复数及 -ed -ing
+func (z *stemmer) step1ab() {
+	if z.b[z.k] == 's' {
+		switch {
+		case z.ends("sses"):
+			z.k -= 2
+		case z.ends("ies"):
+			z.setTo("i")
+		case z.b[z.k-1] != 's':
+			z.k--
+		}
+	}
+
+	if z.ends("eed") {
+		if z.m() > 0 {
+			z.k--
+		}
+		return
+	}
+	if !(z.ends("ed") || z.ends("ing")) || !z.vowelInStem() {
+		return
+	}
+	z.k = z.j
+	switch {
+	case z.ends("at"):
+		z.setTo("ate")
+	case z.ends("bl"):
+		z.setTo("ble")
+	case z.ends("iz"):
+		z.setTo("ize")
+	case z.doubleC(z.k):
+		switch z.b[z.k-1] {
+		case 'l', 's', 'z':
+		default:
+			z.k--
+		}
+	default:
+		z.j = z.k
+		if z.m() == 1 && z.cvc(z.k) {
+			z.setTo("e")
+		}
+	}
+}
+
+// step1c 词干中有元音时 y 改为 i
+func (z *stemmer) step1c() {
+	if z.ends("y") && z.vowelInStem() {
+		z.b[z.k] = 'i'
+	}
+}
+
+// step2 双重后缀改为单个
+func (z *stemmer) step2() {
+	switch z.b[z.k-1] {
+	case 'a':
+		z.replace("ational", "ate", "tional", "tion")
+	case 'c':
+		z.replace("enci", "ence", "anci", "ance")
+	case 'e':
+		z.replace("izer", "ize")
+	case 'l':
+		z.replace("bli", "ble", "alli", "al", "entli", "ent", "eli", "e", "ousli", "ous")
+	case 'o':
+		z.replace("ization", "ize", "ation", "ate", "ator", "ate")
+	case 's':
+		z.replace("alism", "al", "iveness", "ive", "fulness", "ful", "ousness", "ous")
+	case 't':
+		z.replace("aliti", "al", "iviti", "ive", "biliti", "ble")
+	case 'g':
+		z.replace("logi", "log")
+	}
+}
+
+// step3 -ic- -full -ness 等
+func (z *stemmer) step3() {
+	switch z.b[z.k] {
+	case 'e':
+		z.replace("icate", "ic", "ative", "", "alize", "al")
+	case 'i':
+		z.replace("iciti", "ic")
+	case 'l':
+		z.replace("ical", "ic", "ful", "")
+	case 's':
+		z.replace("ness", "")
+	}
+}
+
+// step4 m > 1 时去掉 -ant -ence 等
+func (z *stemmer) step4() {
+	var ok bool
+	switch z.b[z.k-1] {
+	case 'a':
+		ok = z.ends("al")
+	case 'c':
+		ok = z.ends("ance") || z.ends("ence")
+	case 'e':
+		ok = z.ends("er")
+	case 'i':
+		ok = z.ends("ic")
+	case 'l':
+		ok = z.ends("able") || z.ends("ible")
+	case 'n':
+		ok = z.ends("ant") || z.ends("ement") || z.ends("ment") || z.ends("ent")
+	case 'o':
+		ok = (z.ends("ion") && z.j >= 0 && (z.b[z.j] == 's' || z.b[z.j] == 't')) || z.ends("ou")
+	case 's':
+		ok = z.ends("ism")
+	case 't':
+		ok = z.ends("ate") || z.ends("iti")
+	case 'u':
+		ok = z.ends("ous")
+	case 'v':
+		ok = z.ends("ive")
+	case 'z':
+		ok = z.ends("ize")
+	}
+	if ok && z.m() > 1 {
+		z.k = z.j
+	}
+}
+
+// step5 去掉结尾的 e，ll 改为 l
+func (z *stemmer) step5() {
+	z.j = z.k
+	if z.b[z.k] == 'e' {
+		if a := z.m(); a > 1 || (a == 1 && !z.cvc(z.k-1)) {
+			z.k--
+		}
+	}
+	if z.b[z.k] == 'l' && z.doubleC(z.k) && z.m() > 1 {
+		z.k--
+	}
+}
@@ -0,0 +1,59 @@
+package participle
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"ties", "ti"},
+		{"caress", "caress"},
+		{"cats", "cat"},
+		{"feed", "feed"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"bled", "bled"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"conflated", "conflat"},
+		{"troubled", "troubl"},
+		{"sized", "size"},
+		{"hopping", "hop"},
+		{"tanned", "tan"},
+		{"falling", "fall"},
+		{"hissing", "hiss"},
+		{"fizzed", "fizz"},
+		{"failing", "fail"},
+		{"filing", "file"},
+		{"happy", "happi"},
+		{"sky", "sky"},
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"rational", "ration"},
+		{"digitizer", "digit"},
+		{"generalization", "gener"},
+		{"hopeful", "hope"},
+		{"goodness", "good"},
+		{"adjustment", "adjust"},
+		{"connection", "connect"},
+		{"connected", "connect"},
+		{"running", "run"},
+		{"runs", "run"},
+		{"probate", "probat"},
+		{"rate", "rate"},
+		{"controll", "control"},
+		{"roll", "roll"},
+		{"is", "is"},
+		{"café", "café"},
+		{"go2", "go2"},
+		{"Running", "Running"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Stem(tt.in); got != tt.want {
+			t.Errorf("Stem(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
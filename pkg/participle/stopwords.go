@@ -0,0 +1,28 @@
+package participle
+
+// stopWords 英文停用词，不建立索引也不参与搜索
+var stopWords = make(map[string]struct{})
+
+func init() {
+	for _, v := range []string{
+		"a", "about", "above", "after", "again", "against", "all", "am", "an", "and", "any", "are",
+		"as", "at", "be", "because", "been", "before", "being", "below", "between", "both", "but",
+		"by", "can", "could", "did", "do", "does", "doing", "don't", "down", "during", "each", "few",
+		"for", "from", "further", "had", "has", "have", "having", "he", "her", "here", "hers",
+		"herself", "him", "himself", "his", "how", "i", "if", "in", "into", "is", "isn't", "it",
+		"it's", "its", "itself", "just", "me", "more", "most", "my", "myself", "no", "nor", "not",
+		"of", "off", "on", "once", "only", "or", "other", "our", "ours", "ourselves", "out", "over",
+		"own", "same", "she", "should", "so", "some", "such", "than", "that", "the", "their",
+		"theirs", "them", "themselves", "then", "there", "these", "they", "this", "those", "through",
+		"to", "too", "under", "until", "up", "very", "was", "we", "were", "what", "when", "where",
+		"which", "while", "who", "whom", "why", "will", "with", "would", "you", "your", "yours",
+		"yourself", "yourselves",
+	} {
+		stopWords[v] = struct{}{}
+	}
+}
+
+func isStopWord(s string) bool {
+	_, ok := stopWords[s]
+	return ok
+}
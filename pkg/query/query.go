@@ -0,0 +1,231 @@
+package query
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/x2ox/memo/pkg/participle"
+)
+
+// Field 搜索的字段
+type Field uint8
+
+const (
+	FieldAll Field = iota
+	FieldTitle
+)
+
+// Term 搜索词，Tokens 为分词后的结果
+type Term struct {
+	Field  Field
+	Text   string // 原文
+	Tokens []string
+	Phrase bool // 精确短语，词需要相邻
+	Prefix bool // 最后一个词按前缀匹配
+	Fuzzy  int  // 允许的编辑距离，为零精确匹配
+}
+
+// Query 解析后的搜索语句
+//
+//	"exact phrase"  精确短语
+//	-word           排除
+//	title:word      只搜索标题
+//	tag:name        包含 #name 标签，-tag:name 不包含
+//	before:2006-01-02 after:2006-01-02 按创建日期过滤，before 不含当天，after 含当天
+//	                -before:x 等同于 after:x，-after:x 等同于 before:x
+//	a OR b          任意一个
+//	word*           前缀匹配
+//	word~ word~2    模糊匹配，默认编辑距离为 1，仅内置索引支持
+//
+// 多个词之间为 AND，OR 只连接相邻的两个词
+type Query struct {
+	Raw     string
+	Must    [][]Term // 每组内为 OR，组之间为 AND
+	Not     []Term
+	Tags    []string
+	NotTags []string // 排除的标签
+	Before  time.Time
+	After   time.Time
+}
+
+// HasText 是否有需要全文搜索的内容
+func (q *Query) HasText() bool { return len(q.Must) > 0 }
+
+// HasFilter 是否有标签或日期的过滤
+func (q *Query) HasFilter() bool {
+	return len(q.Tags) > 0 || len(q.NotTags) > 0 || !q.Before.IsZero() || !q.After.IsZero()
+}
+
+// IsEmpty 没有任何可以搜索的条件，只有排除词也视为空
+func (q *Query) IsEmpty() bool { return !q.HasText() && !q.HasFilter() }
+
+// Tokens 所有需要匹配的词，不含排除词
+func (q *Query) Tokens() []string {
+	var arr []string
+	for _, group := range q.Must {
+		for _, t := range group {
+			arr = append(arr, t.Tokens...)
+		}
+	}
+	return arr
+}
+
+func Parse(s string) *Query {
+	q := &Query{Raw: s}
+	or := false
+
+	for _, w := range split(s) {
+		if w.or {
+			or = len(q.Must) > 0
+			continue
+		}
+
+		switch w.field {
+		case "tag":
+			if tag := strings.TrimPrefix(w.value, "#"); tag != "" && w.exclude {
+				q.NotTags = append(q.NotTags, tag)
+			} else if tag != "" {
+				q.Tags = append(q.Tags, tag)
+			}
+			or = false
+			continue
+		case "before", "after":
+			if t, ok := parseDate(w.value); ok {
+				if (w.field == "before") != w.exclude { // 排除 before 即为 after，反之亦然
+					q.Before = t
+				} else {
+					q.After = t
+				}
+				or = false
+				continue
+			}
+			w.value, w.field = w.field+":"+w.value, ""
+		}
+
+		term := Term{Text: w.value, Phrase: w.phrase}
+		if !w.phrase {
+			term.Text, term.Prefix, term.Fuzzy = suffix(w.value)
+		}
+		term.Tokens = tokenize(term.Text)
+		if w.field == "title" {
+			term.Field = FieldTitle
+		}
+		if len(term.Tokens) == 0 {
+			continue
+		}
+		if len(term.Tokens) == 1 {
+			term.Phrase = false
+		}
+
+		switch {
+		case w.exclude:
+			q.Not = append(q.Not, term)
+		case or:
+			q.Must[len(q.Must)-1] = append(q.Must[len(q.Must)-1], term)
+		default:
+			q.Must = append(q.Must, []Term{term})
+		}
+		or = false
+	}
+
+	return q
+}
+
+type word struct {
+	value   string
+	field   string
+	phrase  bool
+	exclude bool
+	or      bool
+}
+
+var fields = []string{"title", "tag", "before", "after"}
+
+func split(s string) []word {
+	var (
+		arr []word
+		rs  = []rune(s)
+	)
+
+	for i := 0; i < len(rs); {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		var w word
+		if rs[i] == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) {
+			w.exclude = true
+			i++
+		}
+		for _, f := range fields {
+			if strings.HasPrefix(string(rs[i:]), f+":") && i+len(f)+1 < len(rs) && !unicode.IsSpace(rs[i+len(f)+1]) {
+				w.field = f
+				i += len(f) + 1
+				break
+			}
+		}
+
+		if rs[i] == '"' {
+			j := i + 1
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			w.value, w.phrase = string(rs[i+1:j]), true
+			i = j + 1
+		} else {
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) {
+				j++
+			}
+			w.value = string(rs[i:j])
+			i = j
+		}
+
+		if w.value == "OR" && !w.phrase && !w.exclude && w.field == "" {
+			w.or = true
+		}
+		if w.value != "" || w.or {
+			arr = append(arr, w)
+		}
+	}
+	return arr
+}
+
+// suffix 解析 word* 及 word~N
+func suffix(s string) (text string, prefix bool, fuzzy int) {
+	switch {
+	case len(s) > 1 && strings.HasSuffix(s, "*"):
+		return strings.TrimSuffix(s, "*"), true, 0
+	case len(s) > 1 && strings.HasSuffix(s, "~"):
+		return strings.TrimSuffix(s, "~"), false, 1
+	case len(s) > 2 && s[len(s)-2] == '~' && s[len(s)-1] >= '1' && s[len(s)-1] <= '2':
+		return s[:len(s)-2], false, int(s[len(s)-1] - '0')
+	}
+	return s, false, 0
+}
+
+// tokenize 分词，去掉不包含文字和数字的词
+func tokenize(s string) []string {
+	var arr []string
+	for _, v := range strings.Fields(participle.Parse(s)) {
+		if strings.IndexFunc(v, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsNumber(r)
+		}) >= 0 {
+			arr = append(arr, v)
+		}
+	}
+	return arr
+}
+
+var dateLayouts = []string{"2006-01-02", "2006/01/02", "2006-01", "2006"}
+
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,89 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+	return t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Query
+	}{
+		{"", Query{}},
+		{"memo", Query{Must: [][]Term{{{Text: "memo", Tokens: []string{"memo"}}}}}},
+		{"memo note", Query{Must: [][]Term{
+			{{Text: "memo", Tokens: []string{"memo"}}},
+			{{Text: "note", Tokens: []string{"note"}}},
+		}}},
+		{`"memo note"`, Query{Must: [][]Term{{{Text: "memo note", Tokens: []string{"memo", "note"}, Phrase: true}}}}},
+		{`"memo"`, Query{Must: [][]Term{{{Text: "memo", Tokens: []string{"memo"}}}}}},
+		{"memo -note", Query{
+			Must: [][]Term{{{Text: "memo", Tokens: []string{"memo"}}}},
+			Not:  []Term{{Text: "note", Tokens: []string{"note"}}},
+		}},
+		{"title:memo", Query{Must: [][]Term{{{Field: FieldTitle, Text: "memo", Tokens: []string{"memo"}}}}}},
+		{"memo OR note", Query{Must: [][]Term{{
+			{Text: "memo", Tokens: []string{"memo"}},
+			{Text: "note", Tokens: []string{"note"}},
+		}}}},
+		{"OR memo", Query{Must: [][]Term{{{Text: "memo", Tokens: []string{"memo"}}}}}},
+		{"mem*", Query{Must: [][]Term{{{Text: "mem", Tokens: []string{"mem"}, Prefix: true}}}}},
+		{"memo~", Query{Must: [][]Term{{{Text: "memo", Tokens: []string{"memo"}, Fuzzy: 1}}}}},
+		{"memo~2", Query{Must: [][]Term{{{Text: "memo", Tokens: []string{"memo"}, Fuzzy: 2}}}}},
+		{"tag:work", Query{Tags: []string{"work"}}},
+		{"tag:#work", Query{Tags: []string{"work"}}},
+		{"-tag:work memo", Query{
+			Must:    [][]Term{{{Text: "memo", Tokens: []string{"memo"}}}},
+			NotTags: []string{"work"},
+		}},
+		{"before:2026-10-01", Query{Before: date("2026-10-01")}},
+		{"after:2026-10-01", Query{After: date("2026-10-01")}},
+		{"-before:2026-10-01", Query{After: date("2026-10-01")}},
+		{"-after:2026-10-01", Query{Before: date("2026-10-01")}},
+		{"after:2026", Query{After: date("2026-01-01")}},
+		{"before:soon", Query{Must: [][]Term{{{Text: "before:soon", Tokens: []string{"soon"}}}}}},
+		{"tag:", Query{Must: [][]Term{{{Text: "tag:", Tokens: []string{"tag"}}}}}},
+	}
+	for _, tt := range tests {
+		got := Parse(tt.in)
+		tt.want.Raw = tt.in
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("Parse(%q)\n got  %+v\n want %+v", tt.in, *got, tt.want)
+		}
+	}
+}
+
+func TestQueryState(t *testing.T) {
+	tests := []struct {
+		in                        string
+		hasText, hasFilter, empty bool
+	}{
+		{"", false, false, true},
+		{"memo", true, false, false},
+		{"-memo", false, false, true},
+		{"tag:work", false, true, false},
+		{"-tag:work", false, true, false},
+		{"before:2026-10-01 memo", true, true, false},
+	}
+	for _, tt := range tests {
+		q := Parse(tt.in)
+		if q.HasText() != tt.hasText || q.HasFilter() != tt.hasFilter || q.IsEmpty() != tt.empty {
+			t.Errorf("Parse(%q) HasText=%v HasFilter=%v IsEmpty=%v", tt.in, q.HasText(), q.HasFilter(), q.IsEmpty())
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	got := Parse(`memo OR note "go lang" -skip title:work`).Tokens()
+	want := []string{"memo", "note", "go", "lang", "work"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package speech
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/x2ox/memo/pkg/util"
+)
+
+// Transcriber 语音转文字
+type Transcriber interface {
+	Transcribe(ctx context.Context, filename string) (string, error)
+}
+
+// HTTP 调用本地的语音识别服务，兼容 whisper.cpp server 的 /inference
+// 以及 OpenAI 风格的 /v1/audio/transcriptions，返回 {"text": "..."}
+type HTTP struct {
+	Endpoint string
+	Language string
+	Client   *http.Client
+}
+
+func NewHTTP(endpoint, language string) *HTTP {
+	if language == "" {
+		language = "auto"
+	}
+	return &HTTP{
+		Endpoint: endpoint,
+		Language: language,
+		Client:   &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (h *HTTP) Transcribe(ctx context.Context, filename string) (string, error) {
+	return util.PostFile(ctx, h.Client, h.Endpoint, filename, map[string]string{
+		"response_format": "json",
+		"language":        h.Language,
+	})
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PostFile 以 multipart 上传文件，字段名为 file，解析返回的 {"text": "..."}
+func PostFile(ctx context.Context, client *http.Client, endpoint, filename string, fields map[string]string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var (
+		body bytes.Buffer
+		mw   = multipart.NewWriter(&body)
+		fw   io.Writer
+	)
+	if fw, err = mw.CreateFormFile("file", filepath.Base(filename)); err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(fw, f); err != nil {
+		return "", err
+	}
+	for k, v := range fields {
+		if err = mw.WriteField(k, v); err != nil {
+			return "", err
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var result struct {
+		Text  string `json:"text"`
+		Error string `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.Error != "" {
+		return "", errors.New(result.Error)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
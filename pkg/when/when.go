@@ -0,0 +1,370 @@
+package when
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unit 重复的单位
+type Unit uint8
+
+const (
+	Once Unit = iota
+	Day
+	Week
+	Month
+)
+
+// Rule 解析后的提醒时间
+type Rule struct {
+	At    time.Time // 第一次提醒的时间
+	Unit  Unit      // 重复的单位，Once 不重复
+	Every int       // 每隔几个单位
+	Day   int       // 每月重复的日期，为 0 时使用 At 的日期。月份没有这一天时为该月的最后一天
+}
+
+// ErrUnknown 无法解析的时间
+var ErrUnknown = errors.New("when: unknown time")
+
+// DefaultHour 只有日期时使用的时间
+const DefaultHour = 9
+
+// MaxYears 提醒最远在多少年之后，更远的时间视为无法解析
+const MaxYears = 100
+
+// Parse 解析自然语言的时间，支持
+//
+//	in 3 days / in 30m / in 2 hours     相对时间
+//	today / tonight / tomorrow 9am      某一天，默认 9 点
+//	monday / next friday 18:00          下一个星期几
+//	2026-10-20 / 10-20 21:30            日期
+//	9am / 21:00                         今天，已经过去时为明天
+//	every day / every 2 weeks / every monday 9am / daily / weekly / monthly  重复
+func Parse(s string, now time.Time) (Rule, error) {
+	p := &parser{fields: strings.Fields(strings.ToLower(s)), now: now}
+	r, err := p.parse()
+	if err != nil {
+		return Rule{}, err
+	}
+	if !r.At.After(now) || r.At.After(now.AddDate(MaxYears, 0, 0)) {
+		return Rule{}, ErrUnknown
+	}
+	return r, nil
+}
+
+// Next 在 after 之后的下一次提醒，不重复时返回零值
+func (r Rule) Next(after time.Time) time.Time {
+	if r.Unit == Once {
+		return time.Time{}
+	}
+	every := r.Every
+	if every <= 0 {
+		every = 1
+	}
+	t := r.At
+	for !t.After(after) {
+		switch r.Unit {
+		case Day:
+			t = t.AddDate(0, 0, every)
+		case Week:
+			t = t.AddDate(0, 0, 7*every)
+		case Month:
+			t = addMonths(t, every, r.monthDay())
+		}
+	}
+	return t
+}
+
+func (r Rule) monthDay() int {
+	if r.Day > 0 {
+		return r.Day
+	}
+	return r.At.Day()
+}
+
+// addMonths n 个月后的 day 日，AddDate 会把 1 月 31 日加一个月变为 3 月 3 日，这里取该月的最后一天
+func addMonths(t time.Time, n, day int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// String 重复的规则，如 every 2 days，不重复时为空
+func (r Rule) String() string {
+	var unit string
+	switch r.Unit {
+	case Day:
+		unit = "day"
+	case Week:
+		if r.Every <= 1 {
+			return "every " + strings.ToLower(r.At.Weekday().String())
+		}
+		unit = "week"
+	case Month:
+		unit = "month"
+	default:
+		return ""
+	}
+	if r.Every <= 1 {
+		return "every " + unit
+	}
+	return "every " + strconv.Itoa(r.Every) + " " + unit + "s"
+}
+
+type parser struct {
+	fields []string
+	now    time.Time
+}
+
+func (p *parser) peek() string {
+	if len(p.fields) == 0 {
+		return ""
+	}
+	return p.fields[0]
+}
+
+func (p *parser) next() string {
+	s := p.peek()
+	if len(p.fields) > 0 {
+		p.fields = p.fields[1:]
+	}
+	return s
+}
+
+func (p *parser) today() time.Time {
+	return time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+}
+
+func (p *parser) parse() (Rule, error) {
+	switch p.peek() {
+	case "":
+		return Rule{}, ErrUnknown
+	case "in":
+		p.next()
+		return p.relative()
+	case "every":
+		p.next()
+		return p.every()
+	case "daily":
+		p.next()
+		return p.repeat(Day, 1, p.today())
+	case "weekly":
+		p.next()
+		return p.repeat(Week, 1, p.today())
+	case "monthly":
+		p.next()
+		return p.repeat(Month, 1, p.today())
+	}
+
+	day, explicit, err := p.date()
+	if err != nil {
+		return Rule{}, err
+	}
+	hour, min, hasTime, err := p.clock()
+	if err != nil || len(p.fields) != 0 || (!explicit && !hasTime) {
+		return Rule{}, ErrUnknown
+	}
+	if !hasTime {
+		hour = DefaultHour
+	}
+	at := time.Date(day.Year(), day.Month(), day.Day(), hour, min, 0, 0, p.now.Location())
+	if !explicit && !at.After(p.now) { // 只有时间时，已经过去则为明天
+		at = at.AddDate(0, 0, 1)
+	}
+	return Rule{At: at}, nil
+}
+
+// relative in 3 days、in 30m
+func (p *parser) relative() (Rule, error) {
+	n, unit := 1, p.next()
+	if v, err := strconv.Atoi(unit); err == nil {
+		n, unit = v, p.next()
+	} else if i := strings.IndexFunc(unit, func(r rune) bool { return r < '0' || r > '9' }); i > 0 {
+		n, _ = strconv.Atoi(unit[:i])
+		unit = unit[i:]
+	} else if unit == "a" || unit == "an" {
+		unit = p.next()
+	}
+	// 先限制数量，避免计算时溢出，按分钟计算的上限对其他单位也足够
+	if n <= 0 || n > MaxYears*366*24*60 || len(p.fields) != 0 {
+		return Rule{}, ErrUnknown
+	}
+
+	switch strings.TrimSuffix(unit, "s") {
+	case "m", "min", "minute":
+		return Rule{At: p.now.Add(time.Duration(n) * time.Minute)}, nil
+	case "h", "hr", "hour":
+		return Rule{At: p.now.Add(time.Duration(n) * time.Hour)}, nil
+	case "d", "day":
+		return Rule{At: p.now.AddDate(0, 0, n)}, nil
+	case "w", "week":
+		return Rule{At: p.now.AddDate(0, 0, 7*n)}, nil
+	case "month":
+		return Rule{At: p.now.AddDate(0, n, 0)}, nil
+	}
+	return Rule{}, ErrUnknown
+}
+
+// every every day 9am、every 2 weeks、every monday
+func (p *parser) every() (Rule, error) {
+	n := 1
+	if v, err := strconv.Atoi(p.peek()); err == nil && v > 0 {
+		n = v
+		p.next()
+	}
+
+	word := p.next()
+	if wd, ok := weekday(word); ok && n == 1 {
+		return p.repeat(Week, 1, p.nextWeekday(wd, true))
+	}
+	switch strings.TrimSuffix(word, "s") {
+	case "day":
+		return p.repeat(Day, n, p.today())
+	case "week":
+		return p.repeat(Week, n, p.today())
+	case "month":
+		return p.repeat(Month, n, p.today())
+	}
+	return Rule{}, ErrUnknown
+}
+
+// repeat 从 day 的指定时间开始重复，已经过去时取下一次
+func (p *parser) repeat(unit Unit, every int, day time.Time) (Rule, error) {
+	hour, min, hasTime, err := p.clock()
+	if err != nil || len(p.fields) != 0 {
+		return Rule{}, ErrUnknown
+	}
+	if !hasTime {
+		hour = DefaultHour
+	}
+	r := Rule{
+		At:    time.Date(day.Year(), day.Month(), day.Day(), hour, min, 0, 0, p.now.Location()),
+		Unit:  unit,
+		Every: every,
+	}
+	if !r.At.After(p.now) {
+		r.At = r.Next(p.now)
+	}
+	return r, nil
+}
+
+// date 日期部分，explicit 为是否指定了日期
+func (p *parser) date() (day time.Time, explicit bool, err error) {
+	word := p.peek()
+	switch word {
+	case "today":
+		p.next()
+		return p.today(), true, nil
+	case "tonight":
+		p.next()
+		p.fields = append([]string{"8pm"}, p.fields...)
+		return p.today(), true, nil
+	case "tomorrow":
+		p.next()
+		return p.today().AddDate(0, 0, 1), true, nil
+	case "next":
+		p.next()
+		if wd, ok := weekday(p.next()); ok {
+			return p.nextWeekday(wd, false), true, nil
+		}
+		return time.Time{}, false, ErrUnknown
+	}
+	if wd, ok := weekday(word); ok {
+		p.next()
+		return p.nextWeekday(wd, false), true, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", word, p.now.Location()); err == nil {
+		p.next()
+		return t, true, nil
+	}
+	if t, err := time.ParseInLocation("01-02", word, p.now.Location()); err == nil {
+		p.next()
+		t = time.Date(p.now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, p.now.Location())
+		if t.Before(p.today()) {
+			t = t.AddDate(1, 0, 0)
+		}
+		return t, true, nil
+	}
+	return p.today(), false, nil
+}
+
+// nextWeekday 下一个星期几，today 为 true 时可以是今天
+func (p *parser) nextWeekday(wd time.Weekday, today bool) time.Time {
+	days := (int(wd) - int(p.now.Weekday()) + 7) % 7
+	if days == 0 && !today {
+		days = 7
+	}
+	return p.today().AddDate(0, 0, days)
+}
+
+// clock 时间部分，支持 at 9am、9:30 am、21:00、noon、midnight
+func (p *parser) clock() (hour, min int, ok bool, err error) {
+	if p.peek() == "at" {
+		p.next()
+	}
+	word := p.peek()
+	switch word {
+	case "":
+		return 0, 0, false, nil
+	case "noon":
+		p.next()
+		return 12, 0, true, nil
+	case "midnight":
+		p.next()
+		return 0, 0, true, nil
+	case "morning":
+		p.next()
+		return DefaultHour, 0, true, nil
+	case "evening":
+		p.next()
+		return 20, 0, true, nil
+	}
+	p.next()
+
+	suffix := ""
+	for _, v := range []string{"am", "pm"} {
+		if strings.HasSuffix(word, v) {
+			word, suffix = strings.TrimSuffix(word, v), v
+		}
+	}
+	if suffix == "" && (p.peek() == "am" || p.peek() == "pm") {
+		suffix = p.next()
+	}
+
+	parts := strings.SplitN(word, ":", 2)
+	if hour, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, false, ErrUnknown
+	}
+	if len(parts) == 2 {
+		if min, err = strconv.Atoi(parts[1]); err != nil || min < 0 || min > 59 {
+			return 0, 0, false, ErrUnknown
+		}
+	} else if suffix == "" {
+		return 0, 0, false, ErrUnknown // 单独的数字不是时间
+	}
+	switch {
+	case suffix != "" && (hour < 1 || hour > 12):
+		return 0, 0, false, ErrUnknown
+	case suffix == "am" && hour == 12:
+		hour = 0
+	case suffix == "pm" && hour != 12:
+		hour += 12
+	case hour < 0 || hour > 23:
+		return 0, 0, false, ErrUnknown
+	}
+	return hour, min, true, nil
+}
+
+func weekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] || s == name+"s" {
+			return d, true
+		}
+	}
+	return 0, false
+}
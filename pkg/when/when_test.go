@@ -0,0 +1,134 @@
+package when
+
+import (
+	"testing"
+	"time"
+)
+
+// now 2026-10-19 星期一 10:30
+var now = time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC)
+
+func at(day, hour, min int) time.Time {
+	return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rule
+		err  bool
+	}{
+		{in: "in 30m", want: Rule{At: now.Add(30 * time.Minute)}},
+		{in: "in 30 minutes", want: Rule{At: now.Add(30 * time.Minute)}},
+		{in: "in 2 hours", want: Rule{At: now.Add(2 * time.Hour)}},
+		{in: "in an hour", want: Rule{At: now.Add(time.Hour)}},
+		{in: "in 3 days", want: Rule{At: at(22, 10, 30)}},
+		{in: "in 3d", want: Rule{At: at(22, 10, 30)}},
+		{in: "in a week", want: Rule{At: at(26, 10, 30)}},
+		{in: "in 2 months", want: Rule{At: time.Date(2026, 12, 19, 10, 30, 0, 0, time.UTC)}},
+		{in: "in 0 days", err: true},
+		{in: "in 3 years", err: true},
+		{in: "in 999999999999 days", err: true},
+		{in: "in 999999999999m", err: true},
+		{in: "in 1300 months", err: true},
+		{in: "in 3 days please", err: true},
+
+		{in: "today 21:00", want: Rule{At: at(19, 21, 0)}},
+		{in: "today 9am", err: true},
+		{in: "tonight", want: Rule{At: at(19, 20, 0)}},
+		{in: "tomorrow", want: Rule{At: at(20, DefaultHour, 0)}},
+		{in: "tomorrow 9:30 pm", want: Rule{At: at(20, 21, 30)}},
+		{in: "tomorrow at noon", want: Rule{At: at(20, 12, 0)}},
+		{in: "tomorrow evening", want: Rule{At: at(20, 20, 0)}},
+		{in: "monday", want: Rule{At: at(26, DefaultHour, 0)}},
+		{in: "wed", want: Rule{At: at(21, DefaultHour, 0)}},
+		{in: "next friday 18:00", want: Rule{At: at(23, 18, 0)}},
+		{in: "2026-12-25", want: Rule{At: time.Date(2026, 12, 25, DefaultHour, 0, 0, 0, time.UTC)}},
+		{in: "12-25 8am", want: Rule{At: time.Date(2026, 12, 25, 8, 0, 0, 0, time.UTC)}},
+		{in: "01-02", want: Rule{At: time.Date(2027, 1, 2, DefaultHour, 0, 0, 0, time.UTC)}},
+		{in: "2025-01-01", err: true},
+		{in: "9999-01-01", err: true},
+
+		{in: "9am", want: Rule{At: at(20, 9, 0)}},
+		{in: "21:00", want: Rule{At: at(19, 21, 0)}},
+		{in: "12am", want: Rule{At: at(20, 0, 0)}},
+		{in: "midnight", want: Rule{At: at(20, 0, 0)}},
+		{in: "9", err: true},
+		{in: "25:00", err: true},
+		{in: "13pm", err: true},
+		{in: "9:60", err: true},
+
+		{in: "every day", want: Rule{At: at(20, DefaultHour, 0), Unit: Day, Every: 1}},
+		{in: "daily 22:00", want: Rule{At: at(19, 22, 0), Unit: Day, Every: 1}},
+		{in: "every 2 weeks", want: Rule{At: time.Date(2026, 11, 2, DefaultHour, 0, 0, 0, time.UTC), Unit: Week, Every: 2}},
+		{in: "every monday", want: Rule{At: at(26, DefaultHour, 0), Unit: Week, Every: 1}},
+		{in: "every monday 11:00", want: Rule{At: at(19, 11, 0), Unit: Week, Every: 1}},
+		{in: "monthly", want: Rule{At: time.Date(2026, 11, 19, DefaultHour, 0, 0, 0, time.UTC), Unit: Month, Every: 1}},
+		{in: "every 2 mondays", err: true},
+		{in: "every", err: true},
+
+		{in: "", err: true},
+		{in: "soon", err: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, now)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, want error", tt.in, got.At)
+			}
+			continue
+		}
+		if err != nil || !got.At.Equal(tt.want.At) || got.Unit != tt.want.Unit || got.Every != tt.want.Every {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestRuleNext(t *testing.T) {
+	tests := []struct {
+		rule  Rule
+		after time.Time
+		want  time.Time
+	}{
+		{Rule{At: at(19, 9, 0)}, now, time.Time{}},
+		{Rule{At: at(19, 9, 0), Unit: Day, Every: 1}, now, at(20, 9, 0)},
+		{Rule{At: at(19, 9, 0), Unit: Day, Every: 3}, at(25, 9, 0), at(28, 9, 0)},
+		{Rule{At: at(19, 9, 0), Unit: Week}, now, at(26, 9, 0)},
+		{Rule{At: at(19, 9, 0), Unit: Month, Every: 1}, now, time.Date(2026, 11, 19, 9, 0, 0, 0, time.UTC)},
+		{Rule{At: at(30, 9, 0), Unit: Day, Every: 1}, now, at(30, 9, 0)},
+		{Rule{At: time.Date(2027, 1, 31, 9, 0, 0, 0, time.UTC), Unit: Month, Every: 1},
+			time.Date(2027, 1, 31, 9, 0, 0, 0, time.UTC), time.Date(2027, 2, 28, 9, 0, 0, 0, time.UTC)},
+		{Rule{At: time.Date(2027, 2, 28, 9, 0, 0, 0, time.UTC), Unit: Month, Every: 1, Day: 31},
+			time.Date(2027, 2, 28, 9, 0, 0, 0, time.UTC), time.Date(2027, 3, 31, 9, 0, 0, 0, time.UTC)},
+		{Rule{At: time.Date(2027, 3, 31, 9, 0, 0, 0, time.UTC), Unit: Month, Every: 1, Day: 31},
+			time.Date(2027, 3, 31, 9, 0, 0, 0, time.UTC), time.Date(2027, 4, 30, 9, 0, 0, 0, time.UTC)},
+		{Rule{At: time.Date(2027, 12, 31, 9, 0, 0, 0, time.UTC), Unit: Month, Every: 2},
+			time.Date(2027, 12, 31, 9, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 9, 0, 0, 0, time.UTC)},
+		{Rule{At: time.Date(2027, 1, 29, 9, 0, 0, 0, time.UTC), Unit: Month, Every: 1},
+			time.Date(2027, 5, 1, 9, 0, 0, 0, time.UTC), time.Date(2027, 5, 29, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%+v.Next(%v) = %v, want %v", tt.rule, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{At: now}, ""},
+		{Rule{At: now, Unit: Day, Every: 1}, "every day"},
+		{Rule{At: now, Unit: Day, Every: 2}, "every 2 days"},
+		{Rule{At: now, Unit: Week, Every: 1}, "every monday"},
+		{Rule{At: now, Unit: Week, Every: 3}, "every 3 weeks"},
+		{Rule{At: now, Unit: Month}, "every month"},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
@@ -8,7 +8,7 @@ import (
 type Auth struct{}
 
 func (Auth) Adapter() dandelion.Adapters {
-	return []dandelion.Adapter{&Command{}, inputAdapter, &Inline{}, &Callback{}}
+	return []dandelion.Adapter{&Command{}, inputAdapter, &Inline{}, &InlineChosen{}, &Callback{}}
 }
 func (Auth) IsMatch(c *dandelion.Context) bool { return true }
 func (Auth) Handle(c *dandelion.Context) bool {
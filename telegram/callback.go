@@ -3,6 +3,7 @@ package telegram
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"strconv"
 
 	"github.com/x2ox/memo/db"
@@ -18,6 +19,13 @@ const (
 	CallbackTypeList
 	CallbackTypeUpdateKey
 	CallbackTypeSetCommand
+	CallbackTypeReIndexWord
+	CallbackTypeSemantic
+	CallbackTypeTemplate
+	CallbackTypeJournal
+	CallbackTypeTodo
+	CallbackTypeRemind
+	CallbackTypeReview
 )
 
 func NewCallbackData(t CallbackDataType, param ...string) *string {
@@ -44,17 +52,20 @@ type CallbackData struct {
 }
 
 type (
-	Callback           struct{}
-	CallbackSearch     struct{}
-	CallbackList       struct{}
-	CallbackUpdateKey  struct{}
-	CallbackSetCommand struct{}
+	Callback            struct{}
+	CallbackSearch      struct{}
+	CallbackList        struct{}
+	CallbackUpdateKey   struct{}
+	CallbackSetCommand  struct{}
+	CallbackReIndexWord struct{}
 )
 
 func (Callback) Adapter() dandelion.Adapters {
 	return []dandelion.Adapter{
 		&CallbackList{}, &CallbackSearch{}, &CallbackUpdateKey{},
-		&CallbackSetCommand{},
+		&CallbackSetCommand{}, &CallbackReIndexWord{}, &CallbackTemplate{},
+		&CallbackJournal{}, &CallbackTodo{}, &CallbackRemind{},
+		&CallbackReview{},
 	}
 }
 func (Callback) IsMatch(c *dandelion.Context) bool {
@@ -64,10 +75,12 @@ func (Callback) Handle(*dandelion.Context) bool { return false }
 
 func (CallbackSearch) Adapter() dandelion.Adapters { return nil }
 func (CallbackSearch) IsMatch(c *dandelion.Context) bool {
-	return ParseCallbackData(c.Message.CallbackQuery.Data).Is(CallbackTypeSearch)
+	data := ParseCallbackData(c.Message.CallbackQuery.Data)
+	return data.Is(CallbackTypeSearch) || data.Is(CallbackTypeSemantic)
 }
 func (CallbackSearch) Handle(c *dandelion.Context) bool {
-	param := ParseCallbackData(c.Message.CallbackQuery.Data).Param
+	data := ParseCallbackData(c.Message.CallbackQuery.Data)
+	param := data.Param
 	if len(param) != 2 { // content string, page int
 		return true
 	}
@@ -77,7 +90,11 @@ func (CallbackSearch) Handle(c *dandelion.Context) bool {
 		return true
 	}
 
-	arr, count := db.Search.Search(content, (page-1)*15, 15)
+	header := "Search"
+	if data.Is(CallbackTypeSemantic) {
+		header = "Semantic"
+	}
+	_, arr, count, result := search(content, data.Is(CallbackTypeSemantic), (page-1)*15)
 	countPage := count / 15
 	if count%15 != 0 {
 		countPage++
@@ -87,22 +104,23 @@ func (CallbackSearch) Handle(c *dandelion.Context) bool {
 	if page > 1 {
 		ikb = append(ikb, dandelion.InlineKeyboardButton{
 			Text:         "上一页",
-			CallbackData: NewCallbackData(CallbackTypeSearch, strconv.Itoa(page-1)),
+			CallbackData: NewCallbackData(data.Type, content, strconv.Itoa(page-1)),
 		})
 	}
 	if countPage > int64(page) {
 		ikb = append(ikb, dandelion.InlineKeyboardButton{
 			Text:         "下一页",
-			CallbackData: NewCallbackData(CallbackTypeSearch, strconv.Itoa(page+1)),
+			CallbackData: NewCallbackData(data.Type, content, strconv.Itoa(page+1)),
 		})
 	}
 
 	var buf bytes.Buffer
-	buf.WriteString(model.Header("Search"))
+	buf.WriteString(model.Header(header))
 	buf.WriteString(" `" + content + "`\n\n")
 	for _, v := range arr {
 		buf.WriteString(v.List())
 	}
+	buf.WriteString(facets(result))
 
 	buf.WriteString(model.Pagination(int64(page), countPage, count))
 
@@ -192,6 +210,18 @@ func (CallbackSetCommand) Handle(c *dandelion.Context) bool {
 		{Command: "preview", Description: "「预览草稿」"},
 		{Command: "submit", Description: "「提交内容」"},
 		{Command: "clear", Description: "「清空草稿」"},
+		{Command: "reindex", Description: "「重建索引」"},
+		{Command: "dict", Description: "「分词词典」"},
+		{Command: "pin", Description: "「置顶笔记」"},
+		{Command: "unpin", Description: "「取消置顶」"},
+		{Command: "similar", Description: "「相似笔记」"},
+		{Command: "graph", Description: "「关系图」"},
+		{Command: "title", Description: "「设置标题」"},
+		{Command: "template", Description: "「笔记模板」"},
+		{Command: "journal", Description: "「日记」"},
+		{Command: "todo", Description: "「未完成的任务」"},
+		{Command: "remind", Description: "「提醒」"},
+		{Command: "review", Description: "「复习」"},
 	}))
 	_, _ = c.Send(dandelion.CallbackConfig{
 		CallbackQueryID: c.Message.CallbackQuery.ID,
@@ -199,3 +229,31 @@ func (CallbackSetCommand) Handle(c *dandelion.Context) bool {
 	})
 	return true
 }
+
+func (CallbackReIndexWord) Adapter() dandelion.Adapters { return nil }
+func (CallbackReIndexWord) IsMatch(c *dandelion.Context) bool {
+	return ParseCallbackData(c.Message.CallbackQuery.Data).Type == CallbackTypeReIndexWord
+}
+func (CallbackReIndexWord) Handle(c *dandelion.Context) bool {
+	param := ParseCallbackData(c.Message.CallbackQuery.Data).Param
+	if len(param) != 1 || param[0] == "" {
+		return true
+	}
+
+	notes := db.Note.Contains(param[0])
+	text := "重建完成！"
+	switch err := db.ReIndexNotes(notes); err {
+	case nil:
+		_, _ = c.Send(c.NewEditListMessage(
+			fmt.Sprintf("ฅ՞•ﻌ•՞ฅ 已重建 `%d` 篇笔记的索引", len(notes)), nil))
+	case db.ErrReIndexing:
+		text = "已经在重建了，请稍等"
+	default:
+		text = "似乎发生了点儿什么"
+	}
+	_, _ = c.Send(dandelion.CallbackConfig{
+		CallbackQueryID: c.Message.CallbackQuery.ID,
+		Text:            text,
+	})
+	return true
+}
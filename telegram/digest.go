@@ -0,0 +1,83 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/util"
+	"go.uber.org/zap"
+)
+
+const (
+	digestLimit    = 10 // 每一项最多列出的笔记数量
+	digestReminder = 5  // 列出的即将到来的提醒数量
+)
+
+// digest 发送 at 所在的当天或本周的摘要，补发时 at 为错过的那一次的时间
+func digest(at time.Time) {
+	if err := sendOwner(digestText(at), nil); err != nil {
+		log.Error("send digest error", zap.Error(err))
+	}
+}
+
+func digestText(now time.Time) string {
+	var (
+		buf    bytes.Buffer
+		today  = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		from   = today
+		period = "今天"
+	)
+	buf.WriteString(model.Header("Digest"))
+	if model.Conf.IsWeeklyDigest() {
+		from, period = today.AddDate(0, 0, -(int(today.Weekday())+6)%7), "本周" // 周一为一周的第一天
+		buf.WriteString(" `" + from.Format("2006-01-02") + " ~ " + today.Format("2006-01-02") + "`\n\n")
+	} else {
+		buf.WriteString(" `" + today.Format("2006-01-02") + "`\n\n")
+	}
+
+	to := today.AddDate(0, 0, 1)
+	created, edited := db.Note.CreatedBetween(from, to), db.Note.EditedBetween(from, to)
+	if len(created) == 0 && len(edited) == 0 {
+		buf.WriteString(util.EscapedMarkdownV2(period+"没有新增或编辑的笔记") + "\n")
+	}
+	digestNotes(&buf, fmt.Sprintf("%s新增 `%d` 篇", period, len(created)), created)
+	digestNotes(&buf, fmt.Sprintf("%s编辑 `%d` 篇", period, len(edited)), edited)
+
+	if count := db.Input.Count(); count != 0 {
+		buf.WriteString(fmt.Sprintf("\n草稿箱内还有 `%d` 条未提交的内容\n", count))
+	}
+
+	if arr := db.Reminder.Upcoming(digestReminder); len(arr) != 0 {
+		buf.WriteString("\n即将到来的提醒\n")
+		for _, r := range arr {
+			if note := db.Note.GetWithID(r.NoteID); note != nil {
+				buf.WriteString("`" + r.Describe() + "` " + note.MarkdownLink() + "\n")
+			}
+		}
+	}
+
+	if arr := db.Note.OnThisDay(today); len(arr) != 0 {
+		note := arr[rand.New(rand.NewSource(now.UnixNano())).Intn(len(arr))]
+		buf.WriteString(fmt.Sprintf("\n那年今日 `%s`\n%s\n",
+			note.CreatedAt.In(now.Location()).Format("2006-01-02"), note.MarkdownLink()))
+	}
+	return buf.String()
+}
+
+func digestNotes(buf *bytes.Buffer, title string, arr []*model.Note) {
+	if len(arr) == 0 {
+		return
+	}
+	buf.WriteString("\n" + title + "\n")
+	for i, n := range arr {
+		if i == digestLimit {
+			buf.WriteString("…\n")
+			break
+		}
+		buf.WriteString(n.MarkdownLink() + "\n")
+	}
+}
@@ -2,11 +2,15 @@ package telegram
 
 import (
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/x2ox/memo/db"
 	"github.com/x2ox/memo/model"
 	"github.com/x2ox/memo/pkg/dandelion"
 	"github.com/x2ox/memo/pkg/participle"
+	"github.com/x2ox/memo/pkg/query"
 	"go.uber.org/zap"
 )
 
@@ -19,10 +23,13 @@ func (Inline) Handle(c *dandelion.Context) bool {
 		notes     []*model.Note
 		count     int64
 		offset, _ = strconv.Atoi(c.Message.InlineQuery.Offset)
+		text      = c.Message.InlineQuery.Query
 	)
 
-	if c.Message.InlineQuery.Query != "" {
-		notes, count = db.Search.Search(participle.Parse(c.Message.InlineQuery.Query), offset, 15)
+	if strings.TrimSpace(text) == "" {
+		notes = db.Note.Recent(15)
+	} else {
+		notes, count = db.Search.Search(query.Parse(complete(text)), offset, 15)
 	}
 
 	arr := make([]interface{}, 0, len(notes))
@@ -49,3 +56,50 @@ func (Inline) Handle(c *dandelion.Context) bool {
 	}
 	return true
 }
+
+// completeLimit 最后一个词补全的数量
+const completeLimit = 5
+
+// complete 还在输入的最后一个词，使用词表中以它开头的词补全，以 OR 连接
+func complete(text string) string {
+	head, last := lastWord(text)
+	if last == "" || last == "OR" || strings.ContainsAny(last, `-":*~`) {
+		return text
+	}
+	// 词表中的词没有提取词干，补全 runn 时需要使用原文
+	tokens := participle.Unstemmed(last)
+	if len(tokens) != 1 {
+		return text
+	}
+
+	words := []string{last}
+	for _, v := range db.Complete(tokens[0], completeLimit) {
+		if v != tokens[0] {
+			words = append(words, v)
+		}
+	}
+	return head + strings.Join(words, " OR ")
+}
+
+// lastWord 按最后一个空白字符切分，空白可能是全角空格等多字节的字符。以空白结尾时 last 为空
+func lastWord(text string) (head, last string) {
+	i := strings.LastIndexFunc(text, unicode.IsSpace)
+	if i < 0 {
+		return "", text
+	}
+	_, size := utf8.DecodeRuneInString(text[i:])
+	return text[:i+size], text[i+size:]
+}
+
+type InlineChosen struct{}
+
+func (InlineChosen) Adapter() dandelion.Adapters { return nil }
+func (InlineChosen) IsMatch(c *dandelion.Context) bool {
+	return c.Message.ChosenInlineResult != nil
+}
+func (InlineChosen) Handle(c *dandelion.Context) bool {
+	if id, err := strconv.ParseUint(c.Message.ChosenInlineResult.ResultID, 10, 64); err == nil {
+		db.Note.View(id)
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package telegram
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLastWord(t *testing.T) {
+	tests := []struct {
+		in         string
+		head, last string
+	}{
+		{"runn", "", "runn"},
+		{"go runn", "go ", "runn"},
+		{"go runn ", "go runn ", ""},
+		{"中文　runn", "中文　", "runn"},
+		{"中文　", "中文　", ""},
+		{"a 笔记", "a ", "笔记"},
+		{"a b\tc", "a b\t", "c"},
+		{"　", "　", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		head, last := lastWord(tt.in)
+		if head != tt.head || last != tt.last || !utf8.ValidString(last) {
+			t.Errorf("lastWord(%q) = %q, %q, want %q, %q", tt.in, head, last, tt.head, tt.last)
+		}
+	}
+}
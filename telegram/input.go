@@ -2,14 +2,20 @@ package telegram
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"path"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/x2ox/memo/db"
 	"github.com/x2ox/memo/model"
 	"github.com/x2ox/memo/pkg/dandelion"
-	"github.com/x2ox/memo/pkg/participle"
+	"github.com/x2ox/memo/pkg/index"
+	"github.com/x2ox/memo/pkg/util"
+	"go.uber.org/zap"
 )
 
 var inputAdapter = &Message{mode: model.ModeInput}
@@ -22,32 +28,48 @@ type Message struct {
 func (i *Message) Mode() model.Mode {
 	i.mux.RLock()
 	mode := model.ModeInput
-	if i.mode == model.ModeSearch {
-		mode = model.ModeSearch
+	switch i.mode {
+	case model.ModeSearch, model.ModeSemantic, model.ModeJournal:
+		mode = i.mode
 	}
 	i.mux.RUnlock()
 	return mode
 }
 
+// SwitchMode 输入 -> 搜索 -> 语义搜索 -> 日记 -> 输入，没有配置 embedding 时跳过语义搜索
 func (i *Message) SwitchMode() string {
 	i.mux.Lock()
-	if i.mode == model.ModeInput {
+	switch {
+	case i.mode == model.ModeInput:
 		i.mode = model.ModeSearch
-	} else {
+	case i.mode == model.ModeSearch && embedder != nil:
+		i.mode = model.ModeSemantic
+	case i.mode == model.ModeSearch || i.mode == model.ModeSemantic:
+		i.mode = model.ModeJournal
+	default:
 		i.mode = model.ModeInput
 	}
 	i.mux.Unlock()
 	return i.Mode().String()
 }
 
+// IsSearch 搜索或语义搜索模式
+func (i *Message) IsSearch() bool {
+	mode := i.Mode()
+	return mode == model.ModeSearch || mode == model.ModeSemantic
+}
+
 func (i *Message) Adapter() dandelion.Adapters { return nil }
 func (i *Message) IsMatch(c *dandelion.Context) bool {
 	return c.Message.Message != nil && !c.Message.Message.IsCommand()
 }
 func (i *Message) Handle(c *dandelion.Context) bool {
-	if i.Mode() == model.ModeSearch {
-		searchMode(c)
-	} else {
+	switch mode := i.Mode(); mode {
+	case model.ModeSearch, model.ModeSemantic:
+		searchMode(c, mode == model.ModeSemantic)
+	case model.ModeJournal:
+		journalMode(c)
+	default:
 		inputMode(c)
 	}
 	return true
@@ -58,20 +80,48 @@ type EditedMessage struct{}
 func (EditedMessage) Adapter() dandelion.Adapters       { return nil }
 func (EditedMessage) IsMatch(c *dandelion.Context) bool { return c.Message.EditedMessage != nil }
 func (EditedMessage) Handle(c *dandelion.Context) bool {
-	if c.Message.EditedMessage.Text != "" { // 只处理有文本的编辑
-		if err := db.Input.UpdateContent(c.Message.EditedMessage.MessageID, c.Message.EditedMessage.Text); err != nil {
-		}
+	m := c.Message.EditedMessage
+	content, title, ok := edited(m)
+	if !ok {
+		return true
+	}
+	if title {
+		_ = db.Input.UpdateTitle(m.MessageID, content)
+	} else {
+		_ = db.Input.UpdateContent(m.MessageID, content)
 	}
 	return true
 }
 
-func searchMode(c *dandelion.Context) {
+// edited 编辑后的消息在草稿箱中的内容。/title 的参数为标题，其他命令不是草稿，只处理有文本的编辑
+func edited(m *dandelion.Message) (content string, title, ok bool) {
+	switch {
+	case m.Text == "":
+		return "", false, false
+	case !m.IsCommand():
+		return m.Text, false, true
+	case m.Command() == "title":
+		return strings.TrimSpace(m.CommandArguments()), true, true
+	}
+	return "", false, false
+}
+
+func searchMode(c *dandelion.Context, semantic bool) {
 	if c.Message.Message.Text == "" {
 		c.SendText("ヽ(*。>Д<)o゜ 只能搜索文本哦")
 		return
 	}
 
-	notes, count := db.Search.Search(participle.Parse(c.Message.Message.Text), 0, 15)
+	t, header := CallbackTypeSearch, "Search"
+	if semantic {
+		t, header = CallbackTypeSemantic, "Semantic"
+	}
+	q, notes, count, result := search(c.Message.Message.Text, semantic, 0)
+	if !q.IsEmpty() {
+		if err := db.History.Add(c.Message.Message.Text); err != nil {
+			log.Warn("search history add error", zap.Error(err))
+		}
+	}
 	countPage := count / 15
 	if count%15 != 0 {
 		countPage++
@@ -81,15 +131,16 @@ func searchMode(c *dandelion.Context) {
 	if countPage > 1 {
 		ikb = append(ikb, dandelion.InlineKeyboardButton{
 			Text:         "下一页",
-			CallbackData: NewCallbackData(CallbackTypeSearch, c.Message.Message.Text, strconv.Itoa(2)),
+			CallbackData: NewCallbackData(t, c.Message.Message.Text, strconv.Itoa(2)),
 		})
 	}
 
 	var buf bytes.Buffer
-	buf.WriteString(model.Header("Search `" + c.Message.Message.Text + "`\n\n"))
+	buf.WriteString(model.Header(header + " `" + c.Message.Message.Text + "`\n\n"))
 	for _, v := range notes {
 		buf.WriteString(v.List())
 	}
+	buf.WriteString(facets(result))
 	buf.WriteString(model.Pagination(1, countPage, count))
 
 	_, _ = c.Send(c.NewMessage(
@@ -100,71 +151,189 @@ func searchMode(c *dandelion.Context) {
 	))
 }
 
+// facets 结果中数量最多的标签及月份，没有统计时为空
+func facets(result index.Facets) string {
+	var buf bytes.Buffer
+	if tags := top(result.Tags, 5); len(tags) > 0 {
+		buf.WriteString("\n标签:")
+		for _, v := range tags {
+			buf.WriteString(fmt.Sprintf(" `#%s` %d", util.EscapedMarkdownV2(v), result.Tags[v]))
+		}
+	}
+	if months := top(result.Months, 5); len(months) > 0 {
+		buf.WriteString("\n月份:")
+		for _, v := range months {
+			buf.WriteString(fmt.Sprintf(" `%s` %d", v, result.Months[v]))
+		}
+	}
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// top 数量最多的 n 个
+func top(m map[string]int, n int) []string {
+	arr := make([]string, 0, len(m))
+	for k := range m {
+		arr = append(arr, k)
+	}
+	sort.Slice(arr, func(i, j int) bool {
+		if m[arr[i]] != m[arr[j]] {
+			return m[arr[i]] > m[arr[j]]
+		}
+		return arr[i] < arr[j]
+	})
+	if len(arr) > n {
+		arr = arr[:n]
+	}
+	return arr
+}
+
 func inputMode(c *dandelion.Context) {
 	input := &model.Input{
 		MessageID: c.Message.Message.MessageID,
 	}
+
+	if input.Content = messageContent(c); input.Content != "" { // 跳过
+		if err := db.Input.Add(input); err != nil {
+		}
+	}
+}
+
+// messageContent 消息的文本及附件的链接，每项一行
+func messageContent(c *dandelion.Context) string {
 	var buf bytes.Buffer
 
 	if c.Message.Message.Text != "" {
 		buf.WriteString(c.Message.Message.Text)
 		buf.WriteByte('\n')
 	}
-	if c.Message.Message.Animation != nil {
-		if filename := c.DownloadAndSave(
-			c.Message.Message.Animation.FileID, "/data/memo/file/"); filename != "" {
-			buf.WriteString(linkMarkdown(filename))
+	for _, v := range attachments(c.Message.Message) {
+		if link := saveFile(c, v); link != "" {
+			buf.WriteString(link)
 			buf.WriteByte('\n')
 		}
 	}
-	if len(c.Message.Message.Photo) > 0 {
-		if filename := c.DownloadAndSave(
-			c.Message.Message.Photo[len(c.Message.Message.Photo)-1].FileID,
-			"/data/memo/file/"); filename != "" {
-			buf.WriteString(linkMarkdown(filename))
-			buf.WriteByte('\n')
-		}
+	return buf.String()
+}
+
+type attachmentKind uint8
+
+const (
+	attachmentOther attachmentKind = iota
+	attachmentImage
+	attachmentVoice
+	attachmentDocument
+)
+
+// attachment 消息中附带的文件
+type attachment struct {
+	kind     attachmentKind
+	fileID   string
+	original string
+	mimeType string
+}
+
+func attachments(m *dandelion.Message) []attachment {
+	var arr []attachment
+	if m.Animation != nil {
+		arr = append(arr, attachment{attachmentOther, m.Animation.FileID, m.Animation.FileName, m.Animation.MimeType})
 	}
-	if c.Message.Message.Document != nil {
-		if filename := c.DownloadAndSave(
-			c.Message.Message.Document.FileID, "/data/memo/file/"); filename != "" {
-			buf.WriteString(linkMarkdown(filename))
-			buf.WriteByte('\n')
-		}
+	if len(m.Photo) > 0 {
+		arr = append(arr, attachment{attachmentImage, m.Photo[len(m.Photo)-1].FileID, "", ""})
 	}
-	if c.Message.Message.Video != nil {
-		if filename := c.DownloadAndSave(
-			c.Message.Message.Video.FileID, "/data/memo/file/"); filename != "" {
-			buf.WriteString(linkMarkdown(filename))
-			buf.WriteByte('\n')
+	if m.Document != nil {
+		kind := attachmentDocument
+		if strings.HasPrefix(m.Document.MimeType, "image/") {
+			kind = attachmentImage
 		}
+		arr = append(arr, attachment{kind, m.Document.FileID, m.Document.FileName, m.Document.MimeType})
 	}
-	if c.Message.Message.Audio != nil {
-		if filename := c.DownloadAndSave(
-			c.Message.Message.Audio.FileID, "/data/memo/file/"); filename != "" {
-			buf.WriteString(linkMarkdown(filename))
-			buf.WriteByte('\n')
-		}
+	if m.Video != nil {
+		arr = append(arr, attachment{attachmentOther, m.Video.FileID, m.Video.FileName, m.Video.MimeType})
 	}
-	if c.Message.Message.Voice != nil {
-		if filename := c.DownloadAndSave(
-			c.Message.Message.Voice.FileID, "/data/memo/file/"); filename != "" {
-			buf.WriteString(linkMarkdown(filename))
-			buf.WriteByte('\n')
-		}
+	if m.Audio != nil {
+		arr = append(arr, attachment{attachmentOther, m.Audio.FileID, m.Audio.FileName, m.Audio.MimeType})
 	}
-	if c.Message.Message.VideoNote != nil {
-		if filename := c.DownloadAndSave(
-			c.Message.Message.VideoNote.FileID, "/data/memo/file/"); filename != "" {
-			buf.WriteString(linkMarkdown(filename))
-			buf.WriteByte('\n')
-		}
+	if m.Voice != nil {
+		arr = append(arr, attachment{attachmentVoice, m.Voice.FileID, "", m.Voice.MimeType})
 	}
+	if m.VideoNote != nil {
+		arr = append(arr, attachment{attachmentOther, m.VideoNote.FileID, "", ""})
+	}
+	return arr
+}
 
-	if input.Content = buf.String(); input.Content != "" { // 跳过
-		if err := db.Input.Add(input); err != nil {
-		}
+// saveFile 下载文件并记录原始文件名，返回 Markdown 链接
+func saveFile(c *dandelion.Context, a attachment) string {
+	filename := c.DownloadAndSave(a.fileID, model.Conf.StaticFolder())
+	if filename == "" {
+		return ""
+	}
+	file := &model.File{
+		Name:     filename,
+		Original: a.original,
+		MimeType: a.mimeType,
+	}
+	switch a.kind {
+	case attachmentImage:
+		file.Text = recognize(file)
+	case attachmentDocument:
+		file.Text = extract(file)
+	}
+	if err := db.File.Add(file); err != nil {
+		log.Warn("file record save error", zap.String("name", filename), zap.Error(err))
+	}
+
+	link := linkMarkdown(filename)
+	if a.kind == attachmentVoice {
+		link += transcribe(file)
+	}
+	return link
+}
+
+// recognize 识别图片中的文字，单独存放在附件记录中，不写入正文
+func recognize(file *model.File) string {
+	if recognizer == nil {
+		return ""
+	}
+	text, err := recognizer.Recognize(context.Background(), file.Path())
+	if err != nil {
+		log.Warn("image recognize error", zap.String("name", file.Name), zap.Error(err))
+		return ""
+	}
+	return text
+}
+
+// extract 提取文档中的文字，单独存放在附件记录中，不写入正文
+func extract(file *model.File) string {
+	ext := path.Ext(file.DisplayName())
+	if !extractor.Supported(ext) {
+		return ""
+	}
+	text, err := extractor.Extract(context.Background(), file.Path(), ext)
+	if err != nil {
+		log.Warn("document extract error", zap.String("name", file.Name), zap.Error(err))
+		return ""
+	}
+	return text
+}
+
+// transcribe 语音转文字，以引用块的形式放在语音链接下方，提交后随正文一起索引
+func transcribe(file *model.File) string {
+	if transcriber == nil {
+		return ""
+	}
+	text, err := transcriber.Transcribe(context.Background(), file.Path())
+	if err != nil {
+		log.Warn("voice transcribe error", zap.String("name", file.Name), zap.Error(err))
+		return ""
+	}
+	if text == "" {
+		return ""
 	}
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ") + "\n"
 }
 
 var imgExt = []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".svg", ".webp"}
@@ -0,0 +1,32 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/x2ox/memo/pkg/dandelion"
+)
+
+func TestEdited(t *testing.T) {
+	command := func(text string, length int) *dandelion.Message {
+		return &dandelion.Message{Text: text, Entities: []dandelion.MessageEntity{{Type: "bot_command", Length: length}}}
+	}
+	tests := []struct {
+		m         *dandelion.Message
+		content   string
+		title, ok bool
+	}{
+		{&dandelion.Message{Text: "draft"}, "draft", false, true},
+		{&dandelion.Message{Text: "see /title"}, "see /title", false, true},
+		{&dandelion.Message{}, "", false, false},
+		{command("/title New name ", 6), "New name", true, true},
+		{command("/title@memo_bot  ", 15), "", true, true},
+		{command("/template daily", 9), "", false, false},
+		{command("/titles x", 7), "", false, false},
+	}
+	for _, tt := range tests {
+		content, title, ok := edited(tt.m)
+		if content != tt.content || title != tt.title || ok != tt.ok {
+			t.Errorf("edited(%q) = %q, %v, %v, want %q, %v, %v", tt.m.Text, content, title, ok, tt.content, tt.title, tt.ok)
+		}
+	}
+}
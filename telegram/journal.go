@@ -0,0 +1,153 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/dandelion"
+	"github.com/x2ox/memo/pkg/util"
+	"go.uber.org/zap"
+)
+
+type (
+	CommandJournal  struct{}
+	CallbackJournal struct{}
+)
+
+// journalMode 日记模式下消息追加到当天的日记
+func journalMode(c *dandelion.Context) {
+	text := messageContent(c)
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	note, err := db.Journal.Append(time.Now().In(model.Conf.Location()), text)
+	if err != nil {
+		log.Error("journal append error", zap.Error(err))
+		c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+		return
+	}
+	EmbedLater(note)
+}
+
+// CommandJournal /journal [日期] 查看某一天的日记，默认为今天
+func (CommandJournal) Adapter() dandelion.Adapters       { return nil }
+func (CommandJournal) IsMatch(c *dandelion.Context) bool { return c.CommandIs("journal") }
+func (CommandJournal) Handle(c *dandelion.Context) bool {
+	day, ok := parseJournalDay(c.Message.Message.CommandArguments(), time.Now().In(model.Conf.Location()))
+	if !ok {
+		c.ReplyText("用法: `/journal`、`/journal 2026-10-18`、`/journal 10-18`、`/journal yesterday`")
+		return true
+	}
+	_, _ = c.Send(c.NewMessage(journalDay(day), journalCalendar(day, true)))
+	return true
+}
+
+// parseJournalDay 支持 2006-01-02、01-02、today、yesterday 及 -1 这样的相对天数
+func parseJournalDay(s string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "", "today", "今天":
+		return today, true
+	case "yesterday", "昨天":
+		return today.AddDate(0, 0, -1), true
+	}
+	if n, err := strconv.Atoi(s); err == nil && n <= 0 {
+		return today.AddDate(0, 0, n), true
+	}
+	if t, err := time.ParseInLocation(model.JournalLayout, s, now.Location()); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("01-02", s, now.Location()); err == nil {
+		return time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location()), true
+	}
+	return time.Time{}, false
+}
+
+// journalDay 某一天的日记
+func journalDay(day time.Time) string {
+	var buf bytes.Buffer
+	buf.WriteString(model.Header("Journal"))
+	buf.WriteString(" `" + day.Format(model.JournalLayout) + "`\n\n")
+	if note := db.Journal.Get(day); note != nil {
+		buf.WriteString(note.MarkdownLink())
+		buf.WriteString(fmt.Sprintf(" 一共有 `%d` 条记录", model.JournalEntries(note.Content)))
+	} else {
+		buf.WriteString(util.EscapedMarkdownV2("这一天还没有日记，使用 /mode 切换到日记模式后发送消息即可记录"))
+	}
+	return buf.String()
+}
+
+// journalMonth 某个月的日记数量
+func journalMonth(month time.Time) string {
+	return fmt.Sprintf("%s `%s`\n\n这个月一共有 `%d` 篇日记", model.Header("Journal"),
+		month.Format("2006-01"), len(db.Journal.Days(month.Year(), month.Month())))
+}
+
+// journalCalendar 月历，有日记的日期带有标记，点击跳转，selected 时标出 day
+func journalCalendar(day time.Time, selected bool) *dandelion.InlineKeyboardMarkup {
+	var (
+		first = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		days  = db.Journal.Days(day.Year(), day.Month())
+		none  = NewCallbackData(CallbackNone)
+		ikb   = [][]dandelion.InlineKeyboardButton{{
+			{Text: "‹", CallbackData: NewCallbackData(CallbackTypeJournal, first.AddDate(0, -1, 0).Format("2006-01"))},
+			{Text: first.Format("2006-01"), CallbackData: none},
+			{Text: "›", CallbackData: NewCallbackData(CallbackTypeJournal, first.AddDate(0, 1, 0).Format("2006-01"))},
+		}}
+		row []dandelion.InlineKeyboardButton
+	)
+	for _, v := range []string{"一", "二", "三", "四", "五", "六", "日"} {
+		row = append(row, dandelion.InlineKeyboardButton{Text: v, CallbackData: none})
+	}
+	ikb = append(ikb, row)
+
+	// 周一为一周的第一天
+	row = nil
+	for i := 0; i < (int(first.Weekday())+6)%7; i++ {
+		row = append(row, dandelion.InlineKeyboardButton{Text: " ", CallbackData: none})
+	}
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		text, data := strconv.Itoa(d.Day()), none
+		if days[d.Day()] {
+			text, data = text+"•", NewCallbackData(CallbackTypeJournal, d.Format(model.JournalLayout))
+		}
+		if selected && d.Day() == day.Day() {
+			text = "[" + text + "]"
+		}
+		row = append(row, dandelion.InlineKeyboardButton{Text: text, CallbackData: data})
+		if len(row) == 7 {
+			ikb, row = append(ikb, row), nil
+		}
+	}
+	if len(row) != 0 {
+		for len(row) < 7 {
+			row = append(row, dandelion.InlineKeyboardButton{Text: " ", CallbackData: none})
+		}
+		ikb = append(ikb, row)
+	}
+	return &dandelion.InlineKeyboardMarkup{InlineKeyboard: ikb}
+}
+
+func (CallbackJournal) Adapter() dandelion.Adapters { return nil }
+func (CallbackJournal) IsMatch(c *dandelion.Context) bool {
+	return ParseCallbackData(c.Message.CallbackQuery.Data).Type == CallbackTypeJournal
+}
+func (CallbackJournal) Handle(c *dandelion.Context) bool {
+	param := ParseCallbackData(c.Message.CallbackQuery.Data).Param
+	if len(param) != 1 {
+		return true
+	}
+	// 2006-01-02 为某一天，2006-01 为翻页到某个月
+	if day, err := time.ParseInLocation(model.JournalLayout, param[0], model.Conf.Location()); err == nil {
+		_, _ = c.Send(c.NewEditListMessage(journalDay(day), journalCalendar(day, true)))
+	} else if month, err := time.ParseInLocation("2006-01", param[0], model.Conf.Location()); err == nil {
+		_, _ = c.Send(c.NewEditListMessage(journalMonth(month), journalCalendar(month, false)))
+	}
+	_, _ = c.Send(dandelion.CallbackConfig{CallbackQueryID: c.Message.CallbackQuery.ID})
+	return true
+}
@@ -0,0 +1,220 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/dandelion"
+	"github.com/x2ox/memo/pkg/util"
+	"github.com/x2ox/memo/pkg/when"
+	"go.uber.org/zap"
+)
+
+type (
+	CommandRemind  struct{}
+	CallbackRemind struct{}
+)
+
+const remindLimit = 20 // /remind 最多列出的提醒数量
+
+// remind 发送到期的提醒，提醒保存在数据库中，重启后继续
+func remind(now time.Time) {
+	for _, r := range db.Reminder.Due(now) {
+		note := db.Note.GetWithID(r.NoteID)
+		if note == nil {
+			_, _ = db.Reminder.Cancel(r.ID)
+			continue
+		}
+		// 发送失败时不标记，下次重试
+		if err := sendOwner(remindText(r, note), remindKeyboard(r)); err != nil {
+			log.Error("send reminder error", zap.Uint64("id", r.ID), zap.Error(err))
+			continue
+		}
+		if err := db.Reminder.Fire(r, now); err != nil {
+			log.Error("fire reminder error", zap.Uint64("id", r.ID), zap.Error(err))
+		}
+	}
+}
+
+func remindText(r *model.Reminder, note *model.Note) string {
+	var buf bytes.Buffer
+	buf.WriteString(model.Header("Remind"))
+	buf.WriteString("\n\n")
+	buf.WriteString(note.List())
+	if r.IsRepeat() {
+		buf.WriteString("\n" + util.EscapedMarkdownV2(r.Rule().String()))
+	}
+	return buf.String()
+}
+
+// remindKeyboard 推迟 10 分钟、1 小时、到明天，或者完成。重复的提醒没有完成，使用 /remind 取消
+func remindKeyboard(r *model.Reminder) *dandelion.InlineKeyboardMarkup {
+	id := strconv.FormatUint(r.ID, 10)
+	ikb := [][]dandelion.InlineKeyboardButton{{
+		{Text: "10 分钟后", CallbackData: NewCallbackData(CallbackTypeRemind, "s", id, "10m")},
+		{Text: "1 小时后", CallbackData: NewCallbackData(CallbackTypeRemind, "s", id, "1h")},
+		{Text: "明天", CallbackData: NewCallbackData(CallbackTypeRemind, "s", id, "tomorrow")},
+	}}
+	if !r.IsRepeat() {
+		ikb = append(ikb, []dandelion.InlineKeyboardButton{
+			{Text: "完成", CallbackData: NewCallbackData(CallbackTypeRemind, "d", id)},
+		})
+	}
+	return &dandelion.InlineKeyboardMarkup{InlineKeyboard: ikb}
+}
+
+// CommandRemind /remind <id> <时间> 添加提醒，没有参数时列出提醒
+func (CommandRemind) Adapter() dandelion.Adapters       { return nil }
+func (CommandRemind) IsMatch(c *dandelion.Context) bool { return c.CommandIs("remind") }
+func (CommandRemind) Handle(c *dandelion.Context) bool {
+	args := strings.Fields(c.Message.Message.CommandArguments())
+	if len(args) == 0 {
+		text, ikb := remindList()
+		_, _ = c.Send(c.NewMessage(text, ikb))
+		return true
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil || len(args) < 2 {
+		c.ReplyText("用法: `/remind 12 tomorrow 9am`、`/remind 12 in 3 days`、`/remind 12 every monday`")
+		return true
+	}
+	note := db.Note.GetWithID(id)
+	if note == nil {
+		c.ReplyText(`ヽ\(\*。\>Д<\)o゜ 没有找到这篇笔记`)
+		return true
+	}
+	rule, err := when.Parse(strings.Join(args[1:], " "), time.Now().In(model.Conf.Location()))
+	if err != nil {
+		c.ReplyText("ヽ\\(\\*。\\>Д<\\)o゜ 看不懂这个时间，试试 `tomorrow 9am`、`in 3 days`、`every monday`")
+		return true
+	}
+	r, err := db.Reminder.Add(id, rule)
+	if err != nil {
+		log.Error("add reminder error", zap.Error(err))
+		c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+		return true
+	}
+	c.ReplyText(fmt.Sprintf("ฅ՞•ﻌ•՞ฅ 将在 `%s` 提醒 %s", r.Describe(), note.MarkdownLink()))
+	return true
+}
+
+// remindList 全部提醒，包括已发送但还未完成的，点击按钮取消
+func remindList() (string, *dandelion.InlineKeyboardMarkup) {
+	var (
+		buf bytes.Buffer
+		ikb [][]dandelion.InlineKeyboardButton
+	)
+	buf.WriteString(model.Header("Remind"))
+	buf.WriteString("\n\n")
+	arr := db.Reminder.List(remindLimit)
+	for i, r := range arr {
+		title := "已删除的笔记"
+		if note := db.Note.GetWithID(r.NoteID); note != nil {
+			title = note.Title
+		}
+		mark := ""
+		if r.Fired {
+			mark = "已发送 "
+		}
+		buf.WriteString(fmt.Sprintf("`%d` `%s` %s%s\n", i+1, r.Describe(), mark, util.EscapedMarkdownV2(title)))
+		ikb = append(ikb, []dandelion.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("%d ✕ %s", i+1, truncate(title, todoButtonLen)),
+			CallbackData: NewCallbackData(CallbackTypeRemind, "c", strconv.FormatUint(r.ID, 10)),
+		}})
+	}
+	if len(arr) == 0 {
+		buf.WriteString(util.EscapedMarkdownV2("ฅ՞•ﻌ•՞ฅ 没有提醒，使用 /remind <id> <时间> 添加"))
+	}
+	return buf.String(), &dandelion.InlineKeyboardMarkup{InlineKeyboard: ikb}
+}
+
+// snoozeUntil 推迟到的时间，tomorrow 为明天默认的时间
+func snoozeUntil(s string, now time.Time) (time.Time, bool) {
+	if s == "tomorrow" {
+		rule, err := when.Parse(s, now)
+		return rule.At, err == nil
+	}
+	d, err := time.ParseDuration(s)
+	return now.Add(d), err == nil && d > 0
+}
+
+func (CallbackRemind) Adapter() dandelion.Adapters { return nil }
+func (CallbackRemind) IsMatch(c *dandelion.Context) bool {
+	return ParseCallbackData(c.Message.CallbackQuery.Data).Type == CallbackTypeRemind
+}
+func (CallbackRemind) Handle(c *dandelion.Context) bool {
+	param := ParseCallbackData(c.Message.CallbackQuery.Data).Param
+	if len(param) < 2 { // action string, id uint64, [snooze string]
+		return true
+	}
+	id, err := strconv.ParseUint(param[1], 10, 64)
+	if err != nil {
+		return true
+	}
+
+	answer := "似乎发生了点儿什么"
+	switch param[0] {
+	case "s":
+		if len(param) != 3 {
+			return true
+		}
+		at, ok := snoozeUntil(param[2], time.Now().In(model.Conf.Location()))
+		if !ok {
+			return true
+		}
+		switch r, err := db.Reminder.Snooze(id, at); {
+		case err != nil:
+			log.Error("snooze reminder error", zap.Uint64("id", id), zap.Error(err))
+		case r == nil:
+			answer = "提醒已不存在"
+		default:
+			answer = "将在 " + at.Format("01-02 15:04") + " 再次提醒"
+			clearKeyboard(c)
+		}
+	case "d":
+		switch r, err := db.Reminder.Done(id); {
+		case err != nil:
+			log.Error("done reminder error", zap.Uint64("id", id), zap.Error(err))
+		case r == nil:
+			answer = "提醒已不存在"
+		case r.IsRepeat(): // 之前发送的消息中仍有完成按钮
+			answer = "本次已完成，下次提醒在 " + r.At.In(model.Conf.Location()).Format("01-02 15:04")
+			clearKeyboard(c)
+		default:
+			answer = "已完成"
+			clearKeyboard(c)
+		}
+	case "c":
+		ok, err := db.Reminder.Cancel(id)
+		switch {
+		case err != nil:
+			log.Error("cancel reminder error", zap.Uint64("id", id), zap.Error(err))
+		case !ok:
+			answer = "提醒已不存在"
+		default:
+			answer = "已取消"
+		}
+		text, ikb := remindList()
+		_, _ = c.Send(c.NewEditListMessage(text, ikb))
+	default:
+		return true
+	}
+	_, _ = c.Send(dandelion.CallbackConfig{
+		CallbackQueryID: c.Message.CallbackQuery.ID,
+		Text:            answer,
+	})
+	return true
+}
+
+// clearKeyboard 处理后去掉提醒消息上的按钮，避免重复点击
+func clearKeyboard(c *dandelion.Context) {
+	_, _ = c.Send(dandelion.NewEditMessageReplyMarkup(c.Message.CallbackQuery.Message.Chat.ID,
+		c.Message.CallbackQuery.Message.MessageID,
+		dandelion.InlineKeyboardMarkup{InlineKeyboard: [][]dandelion.InlineKeyboardButton{}}))
+}
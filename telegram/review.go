@@ -0,0 +1,178 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/dandelion"
+	"github.com/x2ox/memo/pkg/util"
+	"go.uber.org/zap"
+)
+
+type (
+	CommandReview  struct{}
+	CallbackReview struct{}
+)
+
+const reviewDigestLimit = 10 // 每天的摘要最多列出的笔记数量
+
+var reviewGrades = []struct {
+	Grade model.Grade
+	Text  string
+}{
+	{model.GradeAgain, "Again"},
+	{model.GradeHard, "Hard"},
+	{model.GradeGood, "Good"},
+	{model.GradeEasy, "Easy"},
+}
+
+// CommandReview /review 复习到期的笔记，/review add <id> 加入复习队列，/review remove <id> 移出
+func (CommandReview) Adapter() dandelion.Adapters       { return nil }
+func (CommandReview) IsMatch(c *dandelion.Context) bool { return c.CommandIs("review") }
+func (CommandReview) Handle(c *dandelion.Context) bool {
+	args := strings.Fields(c.Message.Message.CommandArguments())
+	if len(args) == 0 {
+		text, ikb := reviewCard(time.Now())
+		_, _ = c.Send(c.NewMessage(text, ikb))
+		return true
+	}
+
+	var id uint64
+	if len(args) == 2 {
+		id, _ = strconv.ParseUint(args[1], 10, 64)
+	}
+	if id == 0 || (args[0] != "add" && args[0] != "remove") {
+		c.ReplyText("用法: `/review`、`/review add 12`、`/review remove 12`")
+		return true
+	}
+	note := db.Note.GetWithID(id)
+	if note == nil {
+		c.ReplyText(`ヽ\(\*。\>Д<\)o゜ 没有找到这篇笔记`)
+		return true
+	}
+
+	var (
+		ok   bool
+		err  error
+		text string
+	)
+	if args[0] == "add" {
+		ok, err = db.Review.Add(id, time.Now())
+		text = "已加入复习队列"
+		if !ok {
+			text = "已经在复习队列中了"
+		}
+	} else {
+		ok, err = db.Review.Remove(id)
+		text = "已移出复习队列"
+		if !ok {
+			text = "不在复习队列中"
+		}
+	}
+	if err != nil {
+		log.Error("review queue error", zap.Uint64("id", id), zap.Error(err))
+		c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+		return true
+	}
+	c.ReplyText("ฅ՞•ﻌ•՞ฅ " + note.MarkdownLink() + " " + util.EscapedMarkdownV2(text))
+	return true
+}
+
+// reviewCard 最早到期的笔记及评分按钮
+func reviewCard(now time.Time) (string, *dandelion.InlineKeyboardMarkup) {
+	var buf bytes.Buffer
+	buf.WriteString(model.Header("Review"))
+	buf.WriteString("\n\n")
+
+	for _, r := range db.Review.Due(now, 0) {
+		note := db.Note.GetWithID(r.NoteID)
+		if note == nil {
+			_, _ = db.Review.Remove(r.NoteID)
+			continue
+		}
+
+		buf.WriteString(note.List())
+		buf.WriteString(fmt.Sprintf("\n\n还有 `%d` 篇待复习", db.Review.DueCount(now)))
+		id := strconv.FormatUint(note.ID, 10)
+		row := make([]dandelion.InlineKeyboardButton, 0, len(reviewGrades))
+		for _, v := range reviewGrades {
+			row = append(row, dandelion.InlineKeyboardButton{
+				Text:         v.Text + " · " + r.Preview(v.Grade, now),
+				CallbackData: NewCallbackData(CallbackTypeReview, id, strconv.Itoa(int(v.Grade))),
+			})
+		}
+		return buf.String(), &dandelion.InlineKeyboardMarkup{InlineKeyboard: [][]dandelion.InlineKeyboardButton{row}}
+	}
+
+	buf.WriteString(util.EscapedMarkdownV2("ฅ՞•ﻌ•՞ฅ 现在没有需要复习的笔记，使用 /review add <id> 把笔记加入复习队列"))
+	return buf.String(), nil
+}
+
+// reviewDigest 每天的复习摘要，没有到期的笔记时不发送
+func reviewDigest(now time.Time) {
+	arr := db.Review.Due(now, reviewDigestLimit)
+	if len(arr) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(model.Header("Review"))
+	buf.WriteString(fmt.Sprintf("\n\n今天有 `%d` 篇笔记需要复习\n\n", db.Review.DueCount(now)))
+	for _, r := range arr {
+		if note := db.Note.GetWithID(r.NoteID); note != nil {
+			buf.WriteString(note.MarkdownLink() + "\n")
+		}
+	}
+	if err := sendOwner(buf.String(), &dandelion.InlineKeyboardMarkup{
+		InlineKeyboard: [][]dandelion.InlineKeyboardButton{{{
+			Text:         "开始复习",
+			CallbackData: NewCallbackData(CallbackTypeReview),
+		}}},
+	}); err != nil {
+		log.Error("send review digest error", zap.Error(err))
+	}
+}
+
+func (CallbackReview) Adapter() dandelion.Adapters { return nil }
+func (CallbackReview) IsMatch(c *dandelion.Context) bool {
+	return ParseCallbackData(c.Message.CallbackQuery.Data).Type == CallbackTypeReview
+}
+func (CallbackReview) Handle(c *dandelion.Context) bool {
+	param := ParseCallbackData(c.Message.CallbackQuery.Data).Param
+	now := time.Now()
+
+	var answer string
+	switch len(param) {
+	case 0: // 开始复习
+	case 2: // id uint64, grade uint8
+		id, _ := strconv.ParseUint(param[0], 10, 64)
+		grade, err := strconv.Atoi(param[1])
+		if err != nil || grade < int(model.GradeAgain) || grade > int(model.GradeEasy) {
+			return true
+		}
+		switch r, err := db.Review.Grade(id, model.Grade(grade), now); err {
+		case nil:
+			answer = "下一次复习: " + r.Due.Format("2006-01-02 15:04")
+		case db.ErrNoReview:
+			answer = "已经不在复习队列中了"
+		default:
+			log.Error("grade review error", zap.Uint64("id", id), zap.Error(err))
+			answer = "似乎发生了点儿什么"
+		}
+	default:
+		return true
+	}
+
+	text, ikb := reviewCard(now)
+	_, _ = c.Send(c.NewEditListMessage(text, ikb))
+	_, _ = c.Send(dandelion.CallbackConfig{
+		CallbackQueryID: c.Message.CallbackQuery.ID,
+		Text:            answer,
+	})
+	return true
+}
@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"time"
+
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/dandelion"
+	"go.uber.org/zap"
+)
+
+const scheduleInterval = 30 * time.Second // 检查定时任务的间隔
+
+// schedule 定时发送到期的提醒、每天的复习及摘要，时间使用配置的时区
+func schedule() {
+	review := newDaily("review digest", model.Conf.Review.Digest, func(time.Time) {
+		reviewDigest(time.Now().In(model.Conf.Location()))
+	})
+	summary := newDaily("digest", model.Conf.Digest.Time, digest)
+	if model.Conf.IsWeeklyDigest() {
+		summary = newWeekly("digest", model.Conf.Digest.Time, time.Sunday, digest)
+	}
+
+	now := time.Now().In(model.Conf.Location())
+	for {
+		remind(now)
+		review.run(now)
+		summary.run(now)
+		now = (<-time.After(scheduleInterval)).In(model.Conf.Location())
+	}
+}
+
+// daily 每天或每周到点执行一次的任务。执行的日期保存在数据库中，停机期间错过的在启动后补发一次
+type daily struct {
+	name      string
+	hour, min int
+	weekly    bool
+	weekday   time.Weekday
+	last      string
+	fn        func(at time.Time) // at 为本次应当执行的时间
+}
+
+// newDaily clock 为 15:04 格式的时间，为空或格式不对时返回 nil
+func newDaily(name, clock string, fn func(at time.Time)) *daily {
+	return newSchedule(name, clock, false, 0, fn)
+}
+
+// newWeekly 每周的 weekday 执行
+func newWeekly(name, clock string, weekday time.Weekday, fn func(at time.Time)) *daily {
+	return newSchedule(name, clock, true, weekday, fn)
+}
+
+func newSchedule(name, clock string, weekly bool, weekday time.Weekday, fn func(at time.Time)) *daily {
+	if clock == "" {
+		return nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		log.Warn("schedule time parse error", zap.String("name", name), zap.String("time", clock), zap.Error(err))
+		return nil
+	}
+	return &daily{name: name, hour: t.Hour(), min: t.Minute(), weekly: weekly, weekday: weekday,
+		last: db.Schedule.Last(name), fn: fn}
+}
+
+// latest 不晚于 now 的最近一次应当执行的时间
+func (d *daily) latest(now time.Time) time.Time {
+	at := time.Date(now.Year(), now.Month(), now.Day(), d.hour, d.min, 0, 0, now.Location())
+	if at.After(now) {
+		at = at.AddDate(0, 0, -1)
+	}
+	for d.weekly && at.Weekday() != d.weekday {
+		at = at.AddDate(0, 0, -1)
+	}
+	return at
+}
+
+// run 最近一次应当执行的还没有执行时执行，多次错过也只执行一次。第一次启动时只记录，不补发
+func (d *daily) run(now time.Time) {
+	if d == nil {
+		return
+	}
+	at := d.latest(now)
+	day := at.Format("2006-01-02")
+	if d.last == day {
+		return
+	}
+	first := d.last == ""
+	d.last = day
+	if err := db.Schedule.SetLast(d.name, day); err != nil {
+		log.Error("save schedule error", zap.String("name", d.name), zap.Error(err))
+	}
+	if !first {
+		d.fn(at)
+	}
+}
+
+// sendOwner 主动发送消息给配置的用户
+func sendOwner(text string, ikb *dandelion.InlineKeyboardMarkup) error {
+	_, err := engine.Send(dandelion.MessageConfig{
+		BaseChat:  dandelion.BaseChat{ChatID: model.Conf.TelegramID, ReplyMarkup: ikb},
+		Text:      text,
+		ParseMode: dandelion.ModeMarkdownV2,
+	})
+	return err
+}
@@ -0,0 +1,31 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyLatest(t *testing.T) {
+	at := func(day, hour, min int) time.Time { // 2026-10-19 为星期一
+		return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		d    daily
+		now  time.Time
+		want time.Time
+	}{
+		{daily{hour: 21}, at(19, 21, 0), at(19, 21, 0)},
+		{daily{hour: 21}, at(19, 23, 30), at(19, 21, 0)},
+		{daily{hour: 21}, at(19, 20, 59), at(18, 21, 0)},
+		{daily{hour: 9, min: 30}, at(20, 0, 0), at(19, 9, 30)},
+		{daily{hour: 21, weekly: true, weekday: time.Sunday}, at(19, 10, 0), at(18, 21, 0)},
+		{daily{hour: 21, weekly: true, weekday: time.Sunday}, at(25, 20, 0), at(18, 21, 0)},
+		{daily{hour: 21, weekly: true, weekday: time.Sunday}, at(25, 21, 0), at(25, 21, 0)},
+		{daily{hour: 21, weekly: true, weekday: time.Monday}, at(19, 22, 0), at(19, 21, 0)},
+	}
+	for _, tt := range tests {
+		if got := tt.d.latest(tt.now); !got.Equal(tt.want) {
+			t.Errorf("%+v.latest(%v) = %v, want %v", tt.d, tt.now, got, tt.want)
+		}
+	}
+}
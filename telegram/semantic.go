@@ -0,0 +1,105 @@
+package telegram
+
+import (
+	"context"
+	"time"
+
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/index"
+	"github.com/x2ox/memo/pkg/query"
+	"go.uber.org/zap"
+)
+
+const (
+	embedMaxRunes = 2000 // 超出的部分不参与计算向量
+	embedBatch    = 16   // 补全向量时每次请求的笔记数量
+)
+
+func embedText(n *model.Note) string {
+	rs := []rune(n.Title + "\n" + n.Content)
+	if len(rs) > embedMaxRunes {
+		rs = rs[:embedMaxRunes]
+	}
+	return string(rs)
+}
+
+// embedNotes 计算笔记的向量并保存
+func embedNotes(notes ...*model.Note) error {
+	texts := make([]string, 0, len(notes))
+	for _, v := range notes {
+		texts = append(texts, embedText(v))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	vs, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	for i, v := range notes {
+		if err = db.Vector.Put(v.ID, vs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmbedLater 提交后在后台计算向量，不影响提交
+func EmbedLater(note *model.Note) {
+	if embedder == nil || note == nil {
+		return
+	}
+	go func() {
+		if err := embedNotes(note); err != nil {
+			log.Warn("note embed error", zap.Uint64("id", note.ID), zap.Error(err))
+		}
+	}()
+}
+
+// backfill 为还没有向量的笔记计算向量，启动时在后台执行
+func backfill() {
+	for {
+		notes := db.Vector.Missing(embedBatch)
+		if len(notes) == 0 {
+			return
+		}
+		if err := embedNotes(notes...); err != nil {
+			log.Warn("note embed backfill error", zap.Error(err))
+			return
+		}
+	}
+}
+
+// noteVector 笔记的向量，还没有时立即计算
+func noteVector(note *model.Note) ([]float32, error) {
+	if v := db.Vector.Get(note.ID); v != nil {
+		return v, nil
+	}
+	if err := embedNotes(note); err != nil {
+		return nil, err
+	}
+	return db.Vector.Get(note.ID), nil
+}
+
+// search 语义搜索时合并向量相似度的排序，计算向量失败时只使用全文搜索。
+// 搜索实现支持统计时，同时返回全文搜索结果中的标签及月份
+func search(text string, semantic bool, offset int) (*query.Query, []*model.Note, int64, index.Facets) {
+	q := query.Parse(text)
+	if semantic && embedder != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		vs, err := embedder.Embed(ctx, []string{text})
+		if err == nil {
+			notes, count := db.Semantic(q, vs[0], offset, 15)
+			return q, notes, count, index.Facets{}
+		}
+		log.Warn("query embed error", zap.Error(err))
+	}
+	if f, ok := db.Search.(db.Faceter); ok {
+		notes, count, facets := f.SearchFacets(q, offset, 15)
+		return q, notes, count, facets
+	}
+	notes, count := db.Search.Search(q, offset, 15)
+	return q, notes, count, index.Facets{}
+}
@@ -5,23 +5,51 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/x2ox/memo/db"
 	"github.com/x2ox/memo/model"
 	"github.com/x2ox/memo/pkg/dandelion"
+	"github.com/x2ox/memo/pkg/document"
+	"github.com/x2ox/memo/pkg/embedding"
+	"github.com/x2ox/memo/pkg/ocr"
+	"github.com/x2ox/memo/pkg/participle"
+	"github.com/x2ox/memo/pkg/speech"
+	"github.com/x2ox/memo/pkg/util"
 	"go.uber.org/zap"
 	"go.x2ox.com/blackdatura"
 )
 
 var (
-	log    *zap.Logger
-	engine *dandelion.Engine
+	log         *zap.Logger
+	engine      *dandelion.Engine
+	transcriber speech.Transcriber
+	recognizer  ocr.Recognizer
+	extractor   *document.Extractor
+	embedder    embedding.Embedder
 )
 
 func Init() {
 	log = blackdatura.With("telegram")
 
+	if model.Conf.IsTranscription() {
+		transcriber = speech.NewHTTP(model.Conf.Transcription.Endpoint, model.Conf.Transcription.Language)
+	}
+	extractor = &document.Extractor{PDFToText: model.Conf.Document.PDFToText}
+	if model.Conf.IsEmbedding() {
+		embedder = embedding.NewHTTP(model.Conf.Embedding.Endpoint, model.Conf.Embedding.Model, model.Conf.Embedding.Key)
+		go backfill()
+	}
+	if model.Conf.IsOCR() {
+		if model.Conf.OCR.Tesseract != "" {
+			recognizer = ocr.NewTesseract(model.Conf.OCR.Tesseract, model.Conf.OCR.Language)
+		} else {
+			recognizer = ocr.NewHTTP(model.Conf.OCR.Endpoint, model.Conf.OCR.Language)
+		}
+	}
+
 	var err error
 	if engine, err = dandelion.New(model.Conf.TelegramToken); err != nil {
 		log.Fatal(
@@ -32,6 +60,7 @@ func Init() {
 
 	engine.SetAdapter(&Auth{})
 	model.Username = engine.Username()
+	go schedule()
 
 	if !model.Conf.IsWebhook() {
 		engine.Run()
@@ -69,12 +98,21 @@ type (
 	CommandMode    struct{}
 	CommandStart   struct{}
 	CommandDelete  struct{}
+	CommandReIndex struct{}
+	CommandDict    struct{}
+	CommandPin     struct{}
+	CommandSimilar struct{}
+	CommandGraph   struct{}
+	CommandTitle   struct{}
 )
 
 func (Command) Adapter() dandelion.Adapters {
 	return []dandelion.Adapter{
 		&CommandList{}, &CommandClear{}, &CommandSubmit{}, &CommandMode{},
-		&CommandPreview{}, &CommandStart{}, &CommandDelete{},
+		&CommandPreview{}, &CommandStart{}, &CommandDelete{}, &CommandReIndex{},
+		&CommandDict{}, &CommandPin{}, &CommandSimilar{}, &CommandGraph{},
+		&CommandTitle{}, &CommandTemplate{}, &CommandJournal{},
+		&CommandTodo{}, &CommandRemind{}, &CommandReview{},
 	}
 }
 func (Command) IsMatch(c *dandelion.Context) bool {
@@ -95,6 +133,7 @@ func (CommandSubmit) Handle(c *dandelion.Context) bool {
 		return true
 	}
 
+	EmbedLater(note)
 	c.ReplyText(`ฅ՞•ﻌ•՞ฅ 提交完成`)
 
 	return true
@@ -113,9 +152,13 @@ func (CommandPreview) Adapter() dandelion.Adapters       { return nil }
 func (CommandPreview) IsMatch(c *dandelion.Context) bool { return c.CommandIs("preview") }
 func (CommandPreview) Handle(c *dandelion.Context) bool {
 	u := fmt.Sprintf("%s/preview/%s", model.Conf.Domain, model.NewToken(model.Preview, 0))
+	text := fmt.Sprintf("%s\n 草稿箱内一共有: %d 条输入", model.Header("Preview"), db.Input.Count())
+	if title := db.Input.Title(); title != "" {
+		text += "\n 标题: " + util.EscapedMarkdownV2(title)
+	}
 
 	c.Send(c.NewMessage(
-		fmt.Sprintf("%s\n 草稿箱内一共有: %d 条输入", model.Header("Preview"), db.Input.Count()),
+		text,
 		&dandelion.InlineKeyboardMarkup{
 			InlineKeyboard: [][]dandelion.InlineKeyboardButton{{dandelion.InlineKeyboardButton{
 				Text: "点击预览",
@@ -164,7 +207,36 @@ func (CommandList) Handle(c *dandelion.Context) bool {
 func (CommandMode) Adapter() dandelion.Adapters       { return nil }
 func (CommandMode) IsMatch(c *dandelion.Context) bool { return c.CommandIs("mode") }
 func (CommandMode) Handle(c *dandelion.Context) bool {
-	c.ReplyText(model.SwitchMode(inputAdapter.SwitchMode()))
+	text := model.SwitchMode(inputAdapter.SwitchMode())
+	if !inputAdapter.IsSearch() {
+		c.ReplyText(text)
+		return true
+	}
+
+	// 搜索模式下提供最近的搜索
+	t := CallbackTypeSearch
+	if inputAdapter.Mode() == model.ModeSemantic {
+		t = CallbackTypeSemantic
+	}
+	var ikb [][]dandelion.InlineKeyboardButton
+	for _, v := range db.History.Recent(6) {
+		data := NewCallbackData(t, v, "1")
+		if data == nil || len(*data) > 64 {
+			continue
+		}
+		if len(ikb) == 0 || len(ikb[len(ikb)-1]) == 2 {
+			ikb = append(ikb, nil)
+		}
+		ikb[len(ikb)-1] = append(ikb[len(ikb)-1], dandelion.InlineKeyboardButton{
+			Text:         v,
+			CallbackData: data,
+		})
+	}
+	if len(ikb) == 0 {
+		c.ReplyText(text)
+		return true
+	}
+	_, _ = c.Send(c.NewMessage(text+"\n最近的搜索:", &dandelion.InlineKeyboardMarkup{InlineKeyboard: ikb}))
 	return true
 }
 
@@ -214,3 +286,232 @@ func (CommandDelete) Handle(c *dandelion.Context) bool {
 	c.ReplyText(`\(；￣Д￣）删除完成`)
 	return true
 }
+
+func (CommandReIndex) Adapter() dandelion.Adapters       { return nil }
+func (CommandReIndex) IsMatch(c *dandelion.Context) bool { return c.CommandIs("reindex") }
+func (CommandReIndex) Handle(c *dandelion.Context) bool {
+	reindex(c.Message.Message.Chat.ID)
+	return true
+}
+
+// reindex 在后台重建全文索引，通过编辑同一条消息显示进度
+func reindex(chatID int64) {
+	msg, err := engine.Send(dandelion.MessageConfig{
+		BaseChat:  dandelion.BaseChat{ChatID: chatID},
+		Text:      model.Header("ReIndex") + "\n正在重建索引，完成前搜索可能没有结果",
+		ParseMode: dandelion.ModeMarkdownV2,
+	})
+	if err != nil {
+		return
+	}
+
+	edit := func(text string) {
+		_, _ = engine.Send(dandelion.EditMessageTextConfig{
+			BaseEdit:  dandelion.BaseEdit{ChatID: chatID, MessageID: msg.MessageID},
+			Text:      model.Header("ReIndex") + "\n" + text,
+			ParseMode: dandelion.ModeMarkdownV2,
+		})
+	}
+
+	go func() {
+		last := time.Now()
+		err := db.ReIndex(func(done, total int64) {
+			// 编辑消息有频率限制
+			if done < total && time.Since(last) < 3*time.Second {
+				return
+			}
+			last = time.Now()
+			edit(fmt.Sprintf("正在重建索引: `%d/%d`，完成前搜索可能没有结果", done, total))
+		})
+		switch err {
+		case nil:
+			edit(fmt.Sprintf("ฅ՞•ﻌ•՞ฅ 重建完成，一共 `%d` 篇", db.Note.Count()))
+		case db.ErrReIndexing:
+			edit(`ヽ\(\*。\>Д<\)o゜ 已经在重建了，请稍等`)
+		default:
+			log.Error("reindex error", zap.Error(err))
+			edit(`\(；￣Д￣）似乎发生了点儿什么`)
+		}
+	}()
+}
+
+func (CommandDict) Adapter() dandelion.Adapters       { return nil }
+func (CommandDict) IsMatch(c *dandelion.Context) bool { return c.CommandIs("dict") }
+func (CommandDict) Handle(c *dandelion.Context) bool {
+	args := strings.Fields(c.Message.Message.CommandArguments())
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch {
+	case args[0] == "list":
+		dictList(c)
+	case args[0] == "add" && len(args) >= 2 && len(args) <= 4:
+		w := participle.Word{Word: args[1]}
+		for _, v := range args[2:] {
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				w.Freq = n
+			} else {
+				w.Tag = v
+			}
+		}
+		dictChanged(c, w.Word, participle.AddWord(w))
+	case args[0] == "remove" && len(args) == 2:
+		ok, err := participle.RemoveWord(args[1])
+		if err == nil && !ok {
+			c.ReplyText(`ヽ\(\*。\>Д<\)o゜ 词典中没有这个词`)
+			return true
+		}
+		dictChanged(c, args[1], err)
+	default:
+		c.ReplyText("用法: `/dict add <词> [词频] [词性]`、`/dict remove <词>`、`/dict list`")
+	}
+	return true
+}
+
+// dictMaxList 词典列表最多显示的数量，避免超出消息长度
+const dictMaxList = 100
+
+func dictList(c *dandelion.Context) {
+	arr, err := participle.Words()
+	if err != nil {
+		dictError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(model.Header("Dict"))
+	buf.WriteString(fmt.Sprintf("\n一共有 `%d` 个词\n\n", len(arr)))
+	for i, v := range arr {
+		if i == dictMaxList {
+			buf.WriteString("…")
+			break
+		}
+		buf.WriteString(util.EscapedMarkdownV2(v.String()))
+		buf.WriteByte('\n')
+	}
+	c.ReplyText(buf.String())
+}
+
+// dictChanged 词典修改后，提示重建包含该词的笔记的索引
+func dictChanged(c *dandelion.Context, word string, err error) {
+	if err != nil {
+		dictError(c, err)
+		return
+	}
+
+	count := len(db.Note.Contains(word))
+	text := fmt.Sprintf("ฅ՞•ﻌ•՞ฅ 词典已更新，有 `%d` 篇笔记包含 %s", count, util.EscapedMarkdownV2(word))
+	data := NewCallbackData(CallbackTypeReIndexWord, word)
+	if count == 0 || data == nil || len(*data) > 64 { // callback_data 最长 64 字节
+		if count > 0 {
+			text += "，可以使用 /reindex 重建索引"
+		}
+		c.ReplyText(text)
+		return
+	}
+
+	_, _ = c.Send(c.NewMessage(text, &dandelion.InlineKeyboardMarkup{
+		InlineKeyboard: [][]dandelion.InlineKeyboardButton{{dandelion.InlineKeyboardButton{
+			Text:         "重建这些笔记的索引",
+			CallbackData: data,
+		}}},
+	}))
+}
+
+func dictError(c *dandelion.Context, err error) {
+	if err == participle.ErrNoDict {
+		c.ReplyText(`\(；￣Д￣）没有使用 jieba 分词，不支持用户词典`)
+		return
+	}
+	log.Error("user dict error", zap.Error(err))
+	c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+}
+
+// CommandPin /pin <id> 置顶，/unpin <id> 取消置顶
+func (CommandPin) Adapter() dandelion.Adapters { return nil }
+func (CommandPin) IsMatch(c *dandelion.Context) bool {
+	return c.CommandIs("pin") || c.CommandIs("unpin")
+}
+func (CommandPin) Handle(c *dandelion.Context) bool {
+	id, _ := strconv.ParseUint(c.Message.Message.CommandArguments(), 10, 64)
+	pinned := c.CommandIs("pin")
+	if db.Note.Pin(id, pinned) != nil {
+		c.ReplyText(`\(；￣Д￣）似乎那里不大对`)
+		return true
+	}
+	if pinned {
+		c.ReplyText(`ฅ՞•ﻌ•՞ฅ 置顶完成`)
+	} else {
+		c.ReplyText(`ฅ՞•ﻌ•՞ฅ 已取消置顶`)
+	}
+	return true
+}
+
+// similarLimit /similar 显示的数量
+const similarLimit = 10
+
+func (CommandSimilar) Adapter() dandelion.Adapters       { return nil }
+func (CommandSimilar) IsMatch(c *dandelion.Context) bool { return c.CommandIs("similar") }
+func (CommandSimilar) Handle(c *dandelion.Context) bool {
+	if embedder == nil {
+		c.ReplyText(`\(；￣Д￣）没有配置 embedding，不支持相似笔记`)
+		return true
+	}
+	id, _ := strconv.ParseUint(c.Message.Message.CommandArguments(), 10, 64)
+	note := db.Note.GetWithID(id)
+	if note == nil {
+		c.ReplyText(`\(；￣Д￣）似乎那里不大对`)
+		return true
+	}
+	v, err := noteVector(note)
+	if err != nil {
+		log.Warn("note embed error", zap.Uint64("id", id), zap.Error(err))
+		c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+		return true
+	}
+
+	notes, scores := db.Vector.Similar(id, v, similarLimit)
+	var buf bytes.Buffer
+	buf.WriteString(model.Header("Similar"))
+	buf.WriteString(" " + note.MarkdownLink() + "\n\n")
+	for i, n := range notes {
+		buf.WriteString(fmt.Sprintf("`%.2f` ", scores[i]))
+		buf.WriteString(n.List())
+	}
+	_, _ = c.Send(c.NewMessage(buf.String(), nil))
+	return true
+}
+
+func (CommandGraph) Adapter() dandelion.Adapters       { return nil }
+func (CommandGraph) IsMatch(c *dandelion.Context) bool { return c.CommandIs("graph") }
+func (CommandGraph) Handle(c *dandelion.Context) bool {
+	u := model.GraphLink()
+	c.Send(c.NewMessage(
+		model.Header("Graph"),
+		&dandelion.InlineKeyboardMarkup{
+			InlineKeyboard: [][]dandelion.InlineKeyboardButton{{dandelion.InlineKeyboardButton{
+				Text: "查看关系图",
+				URL:  &u,
+			}}},
+		},
+	))
+	return true
+}
+
+// CommandTitle /title <标题> 设置草稿箱的标题，不带参数时清除
+func (CommandTitle) Adapter() dandelion.Adapters       { return nil }
+func (CommandTitle) IsMatch(c *dandelion.Context) bool { return c.CommandIs("title") }
+func (CommandTitle) Handle(c *dandelion.Context) bool {
+	title := strings.TrimSpace(c.Message.Message.CommandArguments())
+	if err := db.Input.SetTitle(c.Message.Message.MessageID, title); err != nil {
+		c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+		return true
+	}
+	if title == "" {
+		c.ReplyText(`ฅ՞•ﻌ•՞ฅ 已清除标题，提交时从内容中提取`)
+		return true
+	}
+	c.ReplyText("ฅ՞•ﻌ•՞ฅ 标题设置为 " + util.EscapedMarkdownV2(title))
+	return true
+}
@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/dandelion"
+	"github.com/x2ox/memo/pkg/util"
+)
+
+type (
+	CommandTemplate  struct{}
+	CallbackTemplate struct{}
+)
+
+const templateUsage = "用法: `/template add <名称>` 换行后写模板内容、`/template remove <名称>`、`/template <名称>`、`/template list`\n" +
+	"模板中的 `{{date}}` `{{time}}` `{{weekday}}` 使用时会被替换"
+
+// CommandTemplate /template 管理模板，选择模板后放入草稿箱
+func (CommandTemplate) Adapter() dandelion.Adapters       { return nil }
+func (CommandTemplate) IsMatch(c *dandelion.Context) bool { return c.CommandIs("template") }
+func (CommandTemplate) Handle(c *dandelion.Context) bool {
+	args := c.Message.Message.CommandArguments()
+	var content string
+	if i := strings.IndexByte(args, '\n'); i >= 0 {
+		args, content = args[:i], args[i+1:]
+	}
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		fields = []string{"list"}
+	}
+	name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(args), fields[0]))
+
+	switch {
+	case fields[0] == "list" && len(fields) == 1:
+		templateList(c)
+	case fields[0] == "add" && name != "" && strings.TrimSpace(content) != "":
+		if err := db.Template.Save(name, content); err != nil {
+			c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+			return true
+		}
+		c.ReplyText("ฅ՞•ﻌ•՞ฅ 模板 " + util.EscapedMarkdownV2(name) + " 已保存")
+	case fields[0] == "remove" && name != "":
+		ok, err := db.Template.Remove(name)
+		switch {
+		case err != nil:
+			c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+		case !ok:
+			c.ReplyText(`ヽ\(\*。\>Д<\)o゜ 没有这个模板`)
+		default:
+			c.ReplyText("ฅ՞•ﻌ•՞ฅ 模板 " + util.EscapedMarkdownV2(name) + " 已删除")
+		}
+	case content == "" && fields[0] != "add" && fields[0] != "remove":
+		t := db.Template.GetWithName(strings.TrimSpace(args))
+		if t == nil {
+			c.ReplyText(`ヽ\(\*。\>Д<\)o゜ 没有这个模板`)
+			return true
+		}
+		templateUse(c, t, c.Message.Message.MessageID)
+	default:
+		c.ReplyText(templateUsage)
+	}
+	return true
+}
+
+func templateList(c *dandelion.Context) {
+	arr := db.Template.List()
+	if len(arr) == 0 {
+		c.ReplyText("ヽ\\(\\*。\\>Д<\\)o゜ 还没有模板\n" + templateUsage)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(model.Header("Template"))
+	buf.WriteString("\n\n")
+	ikb := make([][]dandelion.InlineKeyboardButton, 0, len(arr))
+	for _, v := range arr {
+		buf.WriteString("`" + strconv.FormatUint(v.ID, 10) + "` " + util.EscapedMarkdownV2(v.Name) + "\n")
+		ikb = append(ikb, []dandelion.InlineKeyboardButton{{
+			Text:         v.Name,
+			CallbackData: NewCallbackData(CallbackTypeTemplate, strconv.FormatUint(v.ID, 10)),
+		}})
+	}
+	_, _ = c.Send(c.NewMessage(buf.String(), &dandelion.InlineKeyboardMarkup{InlineKeyboard: ikb}))
+}
+
+func templateUse(c *dandelion.Context, t *model.NoteTemplate, messageID int) {
+	if _, err := db.Template.Use(t, messageID); err != nil {
+		c.ReplyText(`\(；￣Д￣）似乎发生了点儿什么`)
+		return
+	}
+	c.ReplyText("ฅ՞•ﻌ•՞ฅ 已使用模板 " + util.EscapedMarkdownV2(t.Name) + "，草稿箱内一共有 `" +
+		strconv.FormatInt(db.Input.Count(), 10) + "` 条输入")
+}
+
+func (CallbackTemplate) Adapter() dandelion.Adapters { return nil }
+func (CallbackTemplate) IsMatch(c *dandelion.Context) bool {
+	return ParseCallbackData(c.Message.CallbackQuery.Data).Type == CallbackTypeTemplate
+}
+func (CallbackTemplate) Handle(c *dandelion.Context) bool {
+	param := ParseCallbackData(c.Message.CallbackQuery.Data).Param
+	if len(param) != 1 {
+		return true
+	}
+	id, _ := strconv.ParseUint(param[0], 10, 64)
+	text := "已放入草稿箱"
+	if t := db.Template.GetWithID(id); t == nil {
+		text = "模板已被删除"
+	} else if ok, err := db.Template.Use(t, c.Message.CallbackQuery.Message.MessageID); err != nil {
+		text = "似乎发生了点儿什么"
+	} else if !ok {
+		text = "已经在草稿箱中了"
+	}
+	_, _ = c.Send(dandelion.CallbackConfig{
+		CallbackQueryID: c.Message.CallbackQuery.ID,
+		Text:            text,
+	})
+	return true
+}
@@ -0,0 +1,186 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/x2ox/memo/db"
+	"github.com/x2ox/memo/model"
+	"github.com/x2ox/memo/pkg/dandelion"
+	"github.com/x2ox/memo/pkg/util"
+	"go.uber.org/zap"
+)
+
+type (
+	CommandTodo  struct{}
+	CallbackTodo struct{}
+)
+
+const (
+	todoLimit     = 20 // /todo 最多显示的任务数量，每个任务一个按钮
+	todoRecent    = 5  // 列表下方可以撤销的最近完成的任务数量
+	todoButtonLen = 30 // 按钮上显示的任务内容长度
+)
+
+// doneTask 通过 /todo 完成的任务，只保存在内存中，重启后不能再撤销
+type doneTask struct {
+	NoteID uint64
+	Offset int
+}
+
+var recentDone struct {
+	mux sync.Mutex
+	arr []doneTask // 最新的在前
+}
+
+// setRecentDone 完成时加入最近完成的任务，撤销时移除
+func setRecentDone(t doneTask, done bool) {
+	recentDone.mux.Lock()
+	defer recentDone.mux.Unlock()
+	arr := make([]doneTask, 0, todoRecent)
+	if done {
+		arr = append(arr, t)
+	}
+	for _, v := range recentDone.arr {
+		if v != t && len(arr) < todoRecent {
+			arr = append(arr, v)
+		}
+	}
+	recentDone.arr = arr
+}
+
+func recentDoneTasks() []doneTask {
+	recentDone.mux.Lock()
+	defer recentDone.mux.Unlock()
+	return append([]doneTask(nil), recentDone.arr...)
+}
+
+// CommandTodo /todo 列出全部笔记中未完成的任务，点击按钮完成，最近完成的任务可以撤销
+func (CommandTodo) Adapter() dandelion.Adapters       { return nil }
+func (CommandTodo) IsMatch(c *dandelion.Context) bool { return c.CommandIs("todo") }
+func (CommandTodo) Handle(c *dandelion.Context) bool {
+	text, ikb := todoList()
+	_, _ = c.Send(c.NewMessage(text, ikb))
+	return true
+}
+
+func todoList() (string, *dandelion.InlineKeyboardMarkup) {
+	var (
+		buf   bytes.Buffer
+		ikb   [][]dandelion.InlineKeyboardButton
+		count int
+	)
+	buf.WriteString(model.Header("Todo"))
+	buf.WriteString("\n\n")
+	for _, n := range db.Note.OpenTasks() {
+		if count == todoLimit {
+			buf.WriteString("…\n")
+			break
+		}
+		buf.WriteString(n.MarkdownLink() + "\n")
+		for _, t := range n.Tasks() {
+			if t.Done || count == todoLimit {
+				continue
+			}
+			count++
+			buf.WriteString(fmt.Sprintf("`%d` ☐ %s\n", count, util.EscapedMarkdownV2(t.Text)))
+			ikb = append(ikb, []dandelion.InlineKeyboardButton{{
+				Text:         fmt.Sprintf("%d ☑ %s", count, truncate(t.Text, todoButtonLen)),
+				CallbackData: NewCallbackData(CallbackTypeTodo, strconv.FormatUint(n.ID, 10), strconv.Itoa(t.Offset)),
+			}})
+		}
+		buf.WriteByte('\n')
+	}
+	if count == 0 {
+		buf.WriteString(util.EscapedMarkdownV2("ฅ՞•ﻌ•՞ฅ 没有未完成的任务") + "\n")
+	}
+	return buf.String() + recentDoneList(&ikb), &dandelion.InlineKeyboardMarkup{InlineKeyboard: ikb}
+}
+
+// recentDoneList 最近完成且仍然是完成状态的任务，每个任务一个撤销按钮
+func recentDoneList(ikb *[][]dandelion.InlineKeyboardButton) string {
+	arr := recentDoneTasks()
+	if len(arr) == 0 {
+		return ""
+	}
+	ids := make([]uint64, 0, len(arr))
+	for _, v := range arr {
+		ids = append(ids, v.NoteID)
+	}
+	notes := make(map[uint64]*model.Note)
+	for _, n := range db.Note.Find(ids) {
+		notes[n.ID] = n
+	}
+
+	var buf bytes.Buffer
+	for _, v := range arr {
+		n := notes[v.NoteID]
+		if n == nil {
+			continue
+		}
+		for _, t := range n.Tasks() {
+			if t.Offset != v.Offset || !t.Done {
+				continue
+			}
+			buf.WriteString("☑ " + util.EscapedMarkdownV2(t.Text) + "\n")
+			*ikb = append(*ikb, []dandelion.InlineKeyboardButton{{
+				Text: "↩ " + truncate(t.Text, todoButtonLen),
+				CallbackData: NewCallbackData(CallbackTypeTodo,
+					strconv.FormatUint(n.ID, 10), strconv.Itoa(t.Offset), "undo"),
+			}})
+		}
+	}
+	if buf.Len() == 0 {
+		return ""
+	}
+	return "\n" + util.EscapedMarkdownV2("最近完成") + "\n" + buf.String()
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
+
+func (CallbackTodo) Adapter() dandelion.Adapters { return nil }
+func (CallbackTodo) IsMatch(c *dandelion.Context) bool {
+	return ParseCallbackData(c.Message.CallbackQuery.Data).Type == CallbackTypeTodo
+}
+func (CallbackTodo) Handle(c *dandelion.Context) bool {
+	param := ParseCallbackData(c.Message.CallbackQuery.Data).Param
+	if len(param) != 2 && len(param) != 3 {
+		return true
+	}
+	id, _ := strconv.ParseUint(param[0], 10, 64)
+	offset, err := strconv.Atoi(param[1])
+	if err != nil {
+		return true
+	}
+
+	done, answer := len(param) == 2, "已完成"
+	if !done {
+		answer = "已撤销"
+	}
+	note, err := db.Note.SetTask(id, offset, done)
+	switch err {
+	case nil:
+		setRecentDone(doneTask{NoteID: id, Offset: offset}, done)
+		EmbedLater(note)
+	case db.ErrNoTask:
+		answer = "任务已不存在"
+	default:
+		log.Error("set task error", zap.Uint64("id", id), zap.Error(err))
+		answer = "似乎发生了点儿什么"
+	}
+
+	text, ikb := todoList()
+	_, _ = c.Send(c.NewEditListMessage(text, ikb))
+	_, _ = c.Send(dandelion.CallbackConfig{
+		CallbackQueryID: c.Message.CallbackQuery.ID,
+		Text:            answer,
+	})
+	return true
+}
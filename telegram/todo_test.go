@@ -0,0 +1,22 @@
+package telegram
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetRecentDone(t *testing.T) {
+	recentDone.arr = nil
+	for i := 1; i <= todoRecent+1; i++ {
+		setRecentDone(doneTask{NoteID: uint64(i), Offset: i}, true)
+	}
+	setRecentDone(doneTask{NoteID: 3, Offset: 3}, true)
+	setRecentDone(doneTask{NoteID: 5, Offset: 5}, false)
+	setRecentDone(doneTask{NoteID: 9, Offset: 9}, false)
+
+	want := []doneTask{{3, 3}, {6, 6}, {4, 4}, {2, 2}}
+	if got := recentDoneTasks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("recentDoneTasks() = %v, want %v", got, want)
+	}
+	recentDone.arr = nil
+}
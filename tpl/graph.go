@@ -0,0 +1,221 @@
+package tpl
+
+// graphHTML 笔记关系图，力导向布局，节点大小按被引用的次数
+const graphHTML = `
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width,minimum-scale=1,initial-scale=1,maximum-scale=5,viewport-fit=cover">
+<title>Graph</title>
+<meta name="robots" content="noindex, nofollow">
+<link rel="icon" type="image/png" sizes="32x32" href="https://cdn.jsdelivr.net/gh/x2ox/memo@f535d41e57af7da22a96d3fbab8bd8f3bddab3c1/.data/static/favicon-32x32.png">
+<link rel="icon" type="image/png" sizes="16x16" href="https://cdn.jsdelivr.net/gh/x2ox/memo@f535d41e57af7da22a96d3fbab8bd8f3bddab3c1/.data/static/favicon-16x16.png">
+<meta name="theme-color" content="#ffffff">
+<style>
+html, body {
+	margin: 0;
+	height: 100%;
+	overflow: hidden;
+	font: 12px -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+}
+svg {
+	width: 100%;
+	height: 100%;
+	touch-action: none;
+	cursor: grab;
+}
+line {
+	stroke: #999;
+	stroke-opacity: 0.6;
+}
+circle {
+	fill: #4a90d9;
+	stroke: #fff;
+	stroke-width: 1.5;
+	cursor: pointer;
+}
+a:hover circle {
+	fill: #f5a623;
+}
+text {
+	fill: #333;
+	pointer-events: none;
+}
+#empty {
+	position: absolute;
+	top: 40%;
+	width: 100%;
+	text-align: center;
+	color: #999;
+}
+</style>
+</head>
+<body>
+
+<svg id="graph"><defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0L10,5L0,10z" fill="#999"/></marker></defs><g id="view"><g id="links"></g><g id="nodes"></g></g></svg>
+<div id="empty" hidden>还没有笔记之间的引用，在笔记中写 [[标题]] 试试</div>
+
+<script>
+(function () {
+	var NS = "http://www.w3.org/2000/svg";
+	var svg = document.getElementById("graph");
+	var view = document.getElementById("view");
+	var scale = 1, tx = 0, ty = 0;
+
+	function el(name, attrs, parent) {
+		var e = document.createElementNS(NS, name);
+		for (var k in attrs) e.setAttribute(k, attrs[k]);
+		parent.appendChild(e);
+		return e;
+	}
+	function transform() {
+		view.setAttribute("transform", "translate(" + tx + "," + ty + ") scale(" + scale + ")");
+	}
+
+	fetch({{ . }}, {credentials: "same-origin"}).then(function (r) { return r.json(); }).then(function (g) {
+		if (!g.nodes.length) {
+			document.getElementById("empty").hidden = false;
+			return;
+		}
+
+		var w = svg.clientWidth, h = svg.clientHeight, byID = {};
+		tx = w / 2;
+		ty = h / 2;
+		transform();
+
+		var nodes = g.nodes.map(function (n, i) {
+			var a = i * 2.4, d = 10 * Math.sqrt(i + 1);
+			var node = {id: n.id, r: 5 + 3 * Math.sqrt(n.backlinks), x: d * Math.cos(a), y: d * Math.sin(a), vx: 0, vy: 0};
+			var link = el("a", {href: n.url}, document.getElementById("nodes"));
+			el("title", {}, link).textContent = n.title;
+			node.circle = el("circle", {r: node.r}, link);
+			node.text = el("text", {"text-anchor": "middle"}, link);
+			node.text.textContent = n.title;
+			byID[n.id] = node;
+			return node;
+		});
+		var links = g.links.filter(function (l) {
+			return byID[l.from_id] && byID[l.to_id];
+		}).map(function (l) {
+			return {s: byID[l.from_id], t: byID[l.to_id], line: el("line", {"marker-end": "url(#arrow)"}, document.getElementById("links"))};
+		});
+
+		var alpha = 1, dragged = null, running = false;
+
+		function tick() {
+			var i, j, a, b, dx, dy, d2, d, f;
+			for (i = 0; i < nodes.length; i++) {
+				a = nodes[i];
+				for (j = i + 1; j < nodes.length; j++) {
+					b = nodes[j];
+					dx = b.x - a.x;
+					dy = b.y - a.y;
+					d2 = dx * dx + dy * dy || 0.01;
+					f = 800 * alpha / d2;
+					a.vx -= dx * f; a.vy -= dy * f;
+					b.vx += dx * f; b.vy += dy * f;
+				}
+				a.vx -= a.x * 0.01 * alpha;
+				a.vy -= a.y * 0.01 * alpha;
+			}
+			links.forEach(function (l) {
+				dx = l.t.x - l.s.x;
+				dy = l.t.y - l.s.y;
+				d = Math.sqrt(dx * dx + dy * dy) || 0.01;
+				f = (d - 80) / d * 0.1 * alpha;
+				l.s.vx += dx * f; l.s.vy += dy * f;
+				l.t.vx -= dx * f; l.t.vy -= dy * f;
+			});
+			nodes.forEach(function (n) {
+				if (n === dragged) return;
+				n.vx *= 0.6;
+				n.vy *= 0.6;
+				n.x += n.vx;
+				n.y += n.vy;
+			});
+		}
+		function draw() {
+			nodes.forEach(function (n) {
+				n.circle.setAttribute("cx", n.x);
+				n.circle.setAttribute("cy", n.y);
+				n.text.setAttribute("x", n.x);
+				n.text.setAttribute("y", n.y + n.r + 12);
+			});
+			links.forEach(function (l) {
+				var dx = l.t.x - l.s.x, dy = l.t.y - l.s.y, d = Math.sqrt(dx * dx + dy * dy) || 1;
+				l.line.setAttribute("x1", l.s.x);
+				l.line.setAttribute("y1", l.s.y);
+				l.line.setAttribute("x2", l.t.x - dx / d * (l.t.r + 2));
+				l.line.setAttribute("y2", l.t.y - dy / d * (l.t.r + 2));
+			});
+		}
+		function loop() {
+			tick();
+			draw();
+			alpha *= 0.99;
+			if (alpha > 0.005 || dragged) {
+				requestAnimationFrame(loop);
+			} else {
+				running = false;
+			}
+		}
+		function restart() {
+			alpha = Math.max(alpha, 0.3);
+			if (!running) {
+				running = true;
+				requestAnimationFrame(loop);
+			}
+		}
+		restart();
+
+		// 拖动节点或画布，滚轮缩放
+		var start = null, moved = false;
+		function point(e) {
+			return {x: (e.clientX - tx) / scale, y: (e.clientY - ty) / scale};
+		}
+		svg.addEventListener("pointerdown", function (e) {
+			var circle = e.target.closest && e.target.closest("circle");
+			moved = false;
+			start = {x: e.clientX, y: e.clientY, tx: tx, ty: ty};
+			dragged = null;
+			nodes.forEach(function (n) {
+				if (n.circle === circle) dragged = n;
+			});
+			if (dragged) restart();
+		});
+		window.addEventListener("pointermove", function (e) {
+			if (!start) return;
+			if (Math.abs(e.clientX - start.x) + Math.abs(e.clientY - start.y) > 3) moved = true;
+			if (dragged) {
+				var p = point(e);
+				dragged.x = p.x;
+				dragged.y = p.y;
+				dragged.vx = dragged.vy = 0;
+			} else {
+				tx = start.tx + e.clientX - start.x;
+				ty = start.ty + e.clientY - start.y;
+				transform();
+			}
+		});
+		window.addEventListener("pointerup", function () {
+			start = null;
+			dragged = null;
+		});
+		svg.addEventListener("click", function (e) {
+			if (moved) e.preventDefault();
+		}, true);
+		svg.addEventListener("wheel", function (e) {
+			e.preventDefault();
+			var k = Math.exp(-e.deltaY * 0.001), p = point(e);
+			scale = Math.min(5, Math.max(0.2, scale * k));
+			tx = e.clientX - p.x * scale;
+			ty = e.clientY - p.y * scale;
+			transform();
+		}, {passive: false});
+	});
+})();
+</script>
+
+</body>
+</html>`
@@ -18,11 +18,19 @@ func Load() *template.Template {
 	if util.Exists(filepath.Join(model.Conf.TemplatesFolder(), "tpl.html")) {
 		tpl, err := template.ParseFiles()
 		if err == nil {
-			return tpl
+			return withGraph(tpl)
 		}
 	}
 
 	tpl, _ := template.New("tpl.html").Parse(defaultHTML)
+	return withGraph(tpl)
+}
+
+// withGraph 自定义模板中没有 graph.html 时使用默认的
+func withGraph(tpl *template.Template) *template.Template {
+	if tpl.Lookup("graph.html") == nil {
+		_, _ = tpl.New("graph.html").Parse(graphHTML)
+	}
 	return tpl
 }
 